@@ -0,0 +1,62 @@
+package client
+
+import (
+	"fmt"
+)
+
+const (
+	bitmapsPath = "bitmaps/"
+
+	BitAnd = "AND"
+	BitOr  = "OR"
+	BitXor = "XOR"
+	BitNot = "NOT" // of a single key
+)
+
+type BitPayload struct {
+	Bit int `json:"bit"`
+}
+
+type BitOpPayload struct {
+	Op   string   `json:"op"`
+	Keys []string `json:"keys"`
+}
+
+// returns the bit which was at the offset before
+func (c *Client) SetBit(key string, offset int, bit int) (int, error) {
+	payload, err := encode(BitPayload{Bit: bit})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.request("PUT", fmt.Sprintf("%s%s/bits/%d", bitmapsPath, key, offset), payload)
+	if err != nil {
+		return 0, err
+	}
+	return intFromResponse(getValueFromResponse(resp))
+}
+
+func (c *Client) GetBit(key string, offset int) (int, error) {
+	return intFromResponse(c.get(fmt.Sprintf("%s%s/bits/%d", bitmapsPath, key, offset)))
+}
+
+// number of set bits in bytes from start to end, 0 and -1 for the whole value
+func (c *Client) BitCount(key string, start, end int) (int, error) {
+	return intFromResponse(c.get(fmt.Sprintf("%s%s/count?start=%d&end=%d", bitmapsPath, key, start, end)))
+}
+
+// position of the first bit set to the given one in bytes from start to end, -1 if there is none
+func (c *Client) BitPos(key string, bit int, start, end int) (int, error) {
+	return intFromResponse(c.get(fmt.Sprintf("%s%s/pos?bit=%d&start=%d&end=%d", bitmapsPath, key, bit, start, end)))
+}
+
+// stores the result of the operation over the keys to dest, returns its length in bytes
+func (c *Client) BitOp(op string, dest string, keys ...string) (int, error) {
+	return intFromResponse(c.post(bitmapsPath+dest+"/op", BitOpPayload{Op: op, Keys: keys}))
+}
+
+func intFromResponse(val interface{}, err error) (int, error) {
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
@@ -0,0 +1,44 @@
+package client
+
+import "net/url"
+
+const bloomsPath = "blooms/"
+
+type BloomPayload struct {
+	ErrorRate float64 `json:"error_rate"`
+	Capacity  int     `json:"capacity"`
+}
+
+// creates an empty filter with the given parameters, the key must not exist
+func (c *Client) BFReserve(key string, errorRate float64, capacity int) error {
+	_, err := c.post(bloomsPath+key+"/reserve", BloomPayload{ErrorRate: errorRate, Capacity: capacity})
+	return err
+}
+
+// returns true for elements which were definitely not added before
+func (c *Client) BFAdd(key string, elements ...string) ([]bool, error) {
+	return bools(c.post(bloomsPath+key+"/add", ElementsPayload{Elements: elements}))
+}
+
+// returns true for elements which were probably added
+func (c *Client) BFExists(key string, elements ...string) ([]bool, error) {
+	return bools(c.get(bloomsPath + key + "/exists?" + url.Values{"element": elements}.Encode()))
+}
+
+// all of the keys must have the same error rate and capacity
+func (c *Client) BFMerge(dest string, keys ...string) error {
+	_, err := c.post(bloomsPath+dest+"/merge", KeysPayload{Keys: keys})
+	return err
+}
+
+func bools(val interface{}, err error) ([]bool, error) {
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	result := make([]bool, len(values))
+	for n, v := range values {
+		result[n] = v.(bool)
+	}
+	return result, nil
+}
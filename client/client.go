@@ -9,6 +9,8 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -52,26 +54,69 @@ func LogLatency() Middleware {
 	}
 }
 
+// sends requests to the namespace instead of the default one, except for the admin api
+func InNamespace(name string) Middleware {
+	return func(c httpClient) httpClient {
+		inner := func(r *http.Request) (*http.Response, error) {
+			from, to := "/"+apiPath, "/"+apiPath+nsPath+name+"/"
+			if !strings.HasPrefix(r.URL.Path, from+adminPath) {
+				r.URL.Path = strings.Replace(r.URL.Path, from, to, 1)
+				r.URL.RawPath = strings.Replace(r.URL.RawPath, from, to, 1)
+			}
+			return c.Do(r)
+		}
+		return clientFunc(inner)
+	}
+}
+
+// returned on 412 by CompareAndSwap and Txn, check it with errors.Is
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// keeps the message of the server which names the key
+type versionMismatchError struct {
+	msg string
+}
+
+func (e versionMismatchError) Error() string {
+	return e.msg
+}
+
+func (e versionMismatchError) Unwrap() error {
+	return ErrVersionMismatch
+}
+
 type Client struct {
-	httpClient httpClient
-	apiUrl     string
+	httpClient   httpClient
+	streamClient httpClient // without timeout, for watch streams
+	dialClient   httpClient // dials websockets, for subscriptions
+	apiUrl       string
 }
 
 const (
 	apiVersion = "v1/"
-	apiPath    = "api/" + apiVersion + "keys/"
+	apiPath    = "api/" + apiVersion
+	keysPath   = "keys/"
+	txnPath    = "txn"
+	nsPath     = "ns/"
+	adminPath  = "admin/"
 )
 
 func New(apiUrl string, mw ...Middleware) *Client {
+	var streamClient httpClient = &http.Client{}
+	var dialClient httpClient = clientFunc(dialWebsocket)
 	var httpClient httpClient = &http.Client{
 		Timeout: time.Second * 10,
 	}
 	for _, middleware := range mw {
 		httpClient = middleware(httpClient)
+		streamClient = middleware(streamClient)
+		dialClient = middleware(dialClient)
 	}
 	return &Client{
-		httpClient: httpClient,
-		apiUrl:     apiUrl,
+		httpClient:   httpClient,
+		streamClient: streamClient,
+		dialClient:   dialClient,
+		apiUrl:       apiUrl,
 	}
 }
 
@@ -83,6 +128,53 @@ func (c *Client) Get(key string) (interface{}, error) {
 	return getValueFromResponse(resp)
 }
 
+// version is taken from ETag of the response
+func (c *Client) GetWithVersion(key string) (interface{}, uint64, error) {
+	resp, err := c.makeRequest("GET", key, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	val, err := getValueFromResponse(resp)
+	if err != nil {
+		return nil, 0, err
+	}
+	version, err := parseETag(resp.Header.Get("ETag"))
+	return val, version, err
+}
+
+// version 0 stands for a key which doesn't exist, returns the new version
+func (c *Client) CompareAndSwap(key string, version uint64, value interface{}, ttl time.Duration) (uint64, error) {
+	payload, err := c.getPayload(value, ttl)
+	if err != nil {
+		return 0, err
+	}
+
+	var req *http.Request
+	if version == 0 {
+		req, err = c.newRequest("POST", keysPath+key, payload)
+		if err == nil {
+			req.Header.Set("If-None-Match", "*")
+		}
+	} else {
+		req, err = c.newRequest("PUT", keysPath+key, payload)
+		if err == nil {
+			req.Header.Set("If-Match", `"`+strconv.FormatUint(version, 10)+`"`)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = getValueFromResponse(resp); err != nil {
+		return 0, err
+	}
+	return parseETag(resp.Header.Get("ETag"))
+}
+
 func (c *Client) Set(key string, value interface{}, ttl time.Duration) error {
 	payload, err := c.getPayload(value, ttl)
 	if err != nil {
@@ -118,14 +210,99 @@ func (c *Client) Delete(key string) error {
 	return err
 }
 
+func (c *Client) Incr(key string) (int64, error) {
+	return c.IncrBy(key, 1)
+}
+
+func (c *Client) Decr(key string) (int64, error) {
+	return c.IncrBy(key, -1)
+}
+
+func (c *Client) IncrBy(key string, delta int64) (int64, error) {
+	return c.postInt(keysPath+key+"/incr", IncrPayload{Delta: delta})
+}
+
+func (c *Client) IncrByFloat(key string, delta float64) (float64, error) {
+	val, err := c.post(keysPath+key+"/incrbyfloat", IncrFloatPayload{Delta: delta})
+	if err != nil {
+		return 0, err
+	}
+	return val.(float64), nil
+}
+
+// ttl of a key which is stored until deleted
+const NoExpiration time.Duration = 0
+
+func (c *Client) TTL(key string) (time.Duration, error) {
+	return c.ttl(keysPath + key + "/ttl")
+}
+
+func (c *Client) Expire(key string, ttl time.Duration) error {
+	return c.setExpiration(keysPath+key+"/ttl", TtlPayload{Ttl: ttl})
+}
+
+func (c *Client) ExpireAt(key string, t time.Time) error {
+	return c.setExpiration(keysPath+key+"/ttl", TtlPayload{ExpireAt: t})
+}
+
+func (c *Client) Persist(key string) error {
+	return c.persist(keysPath + key + "/ttl")
+}
+
+func (c *Client) ttl(path string) (time.Duration, error) {
+	val, err := c.get(path)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(val.(float64)), nil
+}
+
+func (c *Client) setExpiration(path string, p TtlPayload) error {
+	payload, err := encode(p)
+	if err != nil {
+		return err
+	}
+	resp, err := c.request("PUT", path, payload)
+	if err != nil {
+		return err
+	}
+	_, err = getValueFromResponse(resp)
+	return err
+}
+
+func (c *Client) persist(path string) error {
+	resp, err := c.request("DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	_, err = getValueFromResponse(resp)
+	return err
+}
+
 type Payload struct {
 	Value interface{}   `json:"value"`
 	Ttl   time.Duration `json:"ttl"`
 }
 
+type IncrPayload struct {
+	Delta int64 `json:"delta"`
+}
+
+type IncrFloatPayload struct {
+	Delta float64 `json:"delta"`
+}
+
+type TtlPayload struct {
+	Ttl      time.Duration `json:"ttl"`
+	ExpireAt time.Time     `json:"expire_at"`
+}
+
 func (c *Client) getPayload(value interface{}, ttl time.Duration) (io.Reader, error) {
-	p := Payload{Value: value, Ttl: ttl}
-	jStr, err := json.Marshal(p)
+	return encode(Payload{Value: value, Ttl: ttl})
+}
+
+func encode(v interface{}) (io.Reader, error) {
+	jStr, err := json.Marshal(v)
 	if err != nil {
 		return nil, err
 	}
@@ -133,26 +310,94 @@ func (c *Client) getPayload(value interface{}, ttl time.Duration) (io.Reader, er
 }
 
 func (c *Client) makeRequest(method string, key string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, c.apiUrl+apiPath+key, body)
+	return c.request(method, keysPath+key, body)
+}
+
+// returns data of the response
+func (c *Client) get(path string) (interface{}, error) {
+	resp, err := c.request("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return getValueFromResponse(resp)
+}
+
+// sends json encoded payload and returns data of the response
+func (c *Client) post(path string, p interface{}) (interface{}, error) {
+	payload, err := encode(p)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.request("POST", path, payload)
+	if err != nil {
+		return nil, err
+	}
+	return getValueFromResponse(resp)
+}
+
+// for int64 results, float64 of the default decoding loses precision above 2^53
+func (c *Client) postInt(path string, p interface{}) (int64, error) {
+	payload, err := encode(p)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.request("POST", path, payload)
+	if err != nil {
+		return 0, err
+	}
+	val, err := decodeResponse(resp, true)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := val.(json.Number)
+	return n.Int64()
+}
+
+func (c *Client) request(method string, path string, body io.Reader) (*http.Response, error) {
+	req, err := c.newRequest(method, path, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 	return c.httpClient.Do(req)
 }
 
+func (c *Client) newRequest(method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.apiUrl+apiPath+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func parseETag(tag string) (uint64, error) {
+	return strconv.ParseUint(strings.Trim(tag, `"`), 10, 64)
+}
+
 func getValueFromResponse(r *http.Response) (interface{}, error) {
+	return decodeResponse(r, false)
+}
+
+// numbers are json.Number instead of float64 with useNumber
+func decodeResponse(r *http.Response, useNumber bool) (interface{}, error) {
 	body, err := ioutil.ReadAll(r.Body)
 	defer r.Body.Close()
 	if err != nil {
 		return nil, err
 	}
 	var resp map[string]interface{}
-	err = json.Unmarshal(body, &resp)
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if useNumber {
+		decoder.UseNumber()
+	}
+	err = decoder.Decode(&resp)
 	if err != nil {
 		return nil, err
 	}
 	if msg := resp["message"]; msg != nil {
+		if r.StatusCode == http.StatusPreconditionFailed {
+			return nil, versionMismatchError{msg.(string)}
+		}
 		return nil, errors.New(msg.(string))
 	}
 	return resp["data"], nil
@@ -4,7 +4,11 @@ package client_test
 // server running on localhost:8080 is required
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/baratov/golang-playground/client"
+	"math"
 	"testing"
 	"time"
 )
@@ -76,3 +80,968 @@ func TestDelete(t *testing.T) {
 		t.Errorf("Expected value is nil, but found %v", val)
 	}
 }
+
+func TestTTL(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	err := c.Set("testKey", "some_string_value", client.NoExpiration)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	ttl, err := c.TTL("testKey")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if ttl != client.NoExpiration {
+		t.Errorf("Expected ttl is %v, but found %v", client.NoExpiration, ttl)
+	}
+
+	err = c.Expire("testKey", time.Minute)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	ttl, err = c.TTL("testKey")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected ttl is in (0, %v], but found %v", time.Minute, ttl)
+	}
+
+	err = c.Persist("testKey")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	err = c.ExpireAt("testKey", time.Now().Add(-time.Second))
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	_, err = c.TTL("testKey")
+	expected := "key 'testKey' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %v, but found %v", expected, err)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testCasKey")
+	v1, err := c.CompareAndSwap("testCasKey", 0, "some_string_value", time.Second)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	_, err = c.CompareAndSwap("testCasKey", 0, "some_other_value", time.Second)
+	expected := "version mismatch for key 'testCasKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %v, but found %v", expected, err)
+	}
+	if !errors.Is(err, client.ErrVersionMismatch) {
+		t.Errorf("Expected %v, but found %v", client.ErrVersionMismatch, err)
+	}
+
+	val, version, err := c.GetWithVersion("testCasKey")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if val != "some_string_value" || version != v1 {
+		t.Errorf("Expected value %v of version %v, but found %v of version %v", "some_string_value", v1, val, version)
+	}
+
+	v2, err := c.CompareAndSwap("testCasKey", version, "some_updated_value", time.Second)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if v2 <= v1 {
+		t.Errorf("Expected version greater than %v, but found %v", v1, v2)
+	}
+}
+
+func TestTxn(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Set("testTxnKey1", "some_string_value", time.Second)
+	c.Set("testTxnKey2", "some_string_value", time.Second)
+	_, version, err := c.GetWithVersion("testTxnKey1")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+
+	results, err := c.Txn().
+		Check("testTxnKey1", version).
+		Update("testTxnKey1", "some_updated_value", time.Second).
+		Delete("testTxnKey2").
+		Get("testTxnKey1").
+		Exec()
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(results) != 4 || results[3] != "some_updated_value" {
+		t.Errorf("Expected the last result is %v, but found %v", "some_updated_value", results)
+	}
+
+	// the same check fails now as the key was updated, nothing is applied
+	_, err = c.Txn().
+		Set("testTxnKey2", "some_string_value", time.Second).
+		Check("testTxnKey1", version).
+		Exec()
+	expected := "version mismatch for key 'testTxnKey1'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %v, but found %v", expected, err)
+	}
+	if !errors.Is(err, client.ErrVersionMismatch) {
+		t.Errorf("Expected %v, but found %v", client.ErrVersionMismatch, err)
+	}
+	if _, err := c.Get("testTxnKey2"); err == nil {
+		t.Errorf("Expected testTxnKey2 not to be written")
+	}
+}
+
+func TestIncrBy(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testCounter")
+	n, err := c.IncrBy("testCounter", 5)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 5 {
+		t.Errorf("Expected value is 5, but found %v", n)
+	}
+
+	n, err = c.Decr("testCounter")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 4 {
+		t.Errorf("Expected value is 4, but found %v", n)
+	}
+
+	f, err := c.IncrByFloat("testCounter", 0.5)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if f != 4.5 {
+		t.Errorf("Expected value is 4.5, but found %v", f)
+	}
+
+	c.Delete("testBigCounter")
+	big := int64(1)<<53 + 1 // not representable by float64
+	n, err = c.IncrBy("testBigCounter", big)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != big {
+		t.Errorf("Expected value is %v, but found %v", big, n)
+	}
+
+	c.Set("testKey", "some_string_value", time.Second)
+	_, err = c.Incr("testKey")
+	expected := "wrong type for key 'testKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %v, but found %v", expected, err)
+	}
+}
+
+func TestList(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testList")
+	n, err := c.RPush("testList", "b", "c", "d")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	n, err = c.LPush("testList", "a")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 4 {
+		t.Errorf("Expected length is 4, but found %v", n)
+	}
+
+	val, err := c.RPop("testList")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if val != "d" {
+		t.Errorf("Expected value is d, but found %v", val)
+	}
+
+	err = c.LTrim("testList", 0, 1)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	values, err := c.LRange("testList", 0, -1)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(values) != 2 || values[0] != "a" || values[1] != "b" {
+		t.Errorf("Expected values are [a b], but found %v", values)
+	}
+	if n, _ := c.LLen("testList"); n != 2 {
+		t.Errorf("Expected length is 2, but found %v", n)
+	}
+}
+
+func TestSet(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testSet1")
+	c.Delete("testSet2")
+	n, err := c.SAdd("testSet1", "a", "b", "c")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 added members, but found %v", n)
+	}
+	c.SAdd("testSet2", "b", "c", "d")
+	c.SRem("testSet2", "c")
+
+	found, err := c.SIsMember("testSet1", "a")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if !found {
+		t.Errorf("Expected a to be a member")
+	}
+	if n, _ := c.SCard("testSet2"); n != 2 {
+		t.Errorf("Expected 2 members, but found %v", n)
+	}
+
+	members, err := c.SInter("testSet1", "testSet2")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(members) != 1 || members[0] != "b" {
+		t.Errorf("Expected intersection is [b], but found %v", members)
+	}
+	if members, _ := c.SUnion("testSet1", "testSet2"); len(members) != 4 {
+		t.Errorf("Expected union of 4 members, but found %v", members)
+	}
+	if members, _ := c.SDiff("testSet1", "testSet2"); len(members) != 2 {
+		t.Errorf("Expected difference of 2 members, but found %v", members)
+	}
+}
+
+func TestSortedSet(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testZSet")
+	n, err := c.ZAdd("testZSet",
+		client.ScoredMember{Member: "alice", Score: 30},
+		client.ScoredMember{Member: "bob", Score: 10},
+		client.ScoredMember{Member: "carol", Score: 20})
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 added members, but found %v", n)
+	}
+
+	score, err := c.ZIncrBy("testZSet", "bob", 25)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if score != 35 {
+		t.Errorf("Expected score is 35, but found %v", score)
+	}
+	if rank, _ := c.ZRank("testZSet", "bob"); rank != 2 {
+		t.Errorf("Expected rank is 2, but found %v", rank)
+	}
+	if score, _ := c.ZScore("testZSet", "carol"); score != 20 {
+		t.Errorf("Expected score is 20, but found %v", score)
+	}
+
+	members, err := c.ZRangeByScore("testZSet", 25, math.Inf(1))
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(members) != 2 || members[0].Member != "alice" || members[1].Member != "bob" {
+		t.Errorf("Expected range is [alice bob], but found %v", members)
+	}
+
+	c.ZRem("testZSet", "carol")
+	members, err = c.ZRangeByRank("testZSet", 0, -1)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(members) != 2 || members[0].Member != "alice" {
+		t.Errorf("Expected range is [alice bob], but found %v", members)
+	}
+}
+
+func TestHash(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testHash")
+	created, err := c.HSet("testHash", "name", "alice")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if !created {
+		t.Errorf("Expected the field to be created")
+	}
+	c.HSet("testHash", "city", "berlin")
+
+	val, err := c.HGet("testHash", "name")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if val != "alice" {
+		t.Errorf("Expected value is alice, but found %v", val)
+	}
+
+	n, err := c.HIncrBy("testHash", "visits", 3)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 3 {
+		t.Errorf("Expected value is 3, but found %v", n)
+	}
+
+	if removed, _ := c.HDel("testHash", "city"); !removed {
+		t.Errorf("Expected the field to be removed")
+	}
+	if keys, _ := c.HKeys("testHash"); len(keys) != 2 || keys[0] != "name" || keys[1] != "visits" {
+		t.Errorf("Expected keys are [name visits], but found %v", keys)
+	}
+	if n, _ := c.HLen("testHash"); n != 2 {
+		t.Errorf("Expected 2 fields, but found %v", n)
+	}
+	fields, err := c.HGetAll("testHash")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(fields) != 2 || fields["name"] != "alice" {
+		t.Errorf("Expected fields are map[name:alice visits:3], but found %v", fields)
+	}
+}
+
+func TestHashFieldTTL(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testSession")
+	c.HSet("testSession", "user", "alice")
+	if _, err := c.HSetEx("testSession", "token", "secret", time.Millisecond*200); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+
+	ttl, err := c.HTTL("testSession", "token")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if ttl <= 0 || ttl > time.Millisecond*200 {
+		t.Errorf("Expected ttl is up to 200ms, but found %v", ttl)
+	}
+
+	if err := c.HExpire("testSession", "user", time.Minute); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if err := c.HPersist("testSession", "user"); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if ttl, _ := c.HTTL("testSession", "user"); ttl != client.NoExpiration {
+		t.Errorf("Expected no expiration, but found %v", ttl)
+	}
+
+	time.Sleep(time.Millisecond * 250)
+	if _, err := c.HGet("testSession", "token"); err == nil {
+		t.Errorf("Expected the field to be expired")
+	}
+	if val, _ := c.HGet("testSession", "user"); val != "alice" {
+		t.Errorf("Expected value is alice, but found %v", val)
+	}
+}
+
+func TestStream(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testStream")
+	first, err := c.XAdd("testStream", map[string]interface{}{"n": 1}, client.NoMaxLen)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	c.XAdd("testStream", map[string]interface{}{"n": 2}, client.NoMaxLen)
+
+	entries, err := c.XRange("testStream", client.FirstID, client.LastID, 0)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(entries) != 2 || entries[0].ID != first || entries[1].Fields["n"] != 2.0 {
+		t.Errorf("Expected 2 entries starting with %v, but found %v", first, entries)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		c.XAdd("testStream", map[string]interface{}{"n": 3}, 2)
+	}()
+	entries, err = c.XRead("testStream", client.NewEntriesID, 0, time.Second*2)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(entries) != 1 || entries[0].Fields["n"] != 3.0 {
+		t.Errorf("Expected the third entry, but found %v", entries)
+	}
+	if n, _ := c.XLen("testStream"); n != 2 {
+		t.Errorf("Expected 2 entries after trimming, but found %v", n)
+	}
+}
+
+func TestConsumerGroup(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testGroupStream")
+	if err := c.XGroupCreate("testGroupStream", "workers", "0"); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	c.XAdd("testGroupStream", map[string]interface{}{"n": 1}, client.NoMaxLen)
+	c.XAdd("testGroupStream", map[string]interface{}{"n": 2}, client.NoMaxLen)
+
+	entries, err := c.XReadGroup("testGroupStream", "workers", "alice", 1, 0)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, but found %v", entries)
+	}
+	if entries[0].Fields["n"] != 1.0 {
+		t.Errorf("Expected the first entry, but found %v", entries)
+	}
+	c.XReadGroup("testGroupStream", "workers", "bob", 0, time.Second)
+
+	acked, err := c.XAck("testGroupStream", "workers", entries[0].ID)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if acked != 1 {
+		t.Errorf("Expected 1 acked entry, but found %v", acked)
+	}
+	pending, err := c.XPending("testGroupStream", "workers")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(pending) != 1 || pending[0].Consumer != "bob" || pending[0].Deliveries != 1 {
+		t.Errorf("Expected 1 entry pending for bob, but found %+v", pending)
+	}
+}
+
+func TestBitmap(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testBits1")
+	c.Delete("testBits2")
+	old, err := c.SetBit("testBits1", 3, 1)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if old != 0 {
+		t.Errorf("Expected old bit is 0, but found %v", old)
+	}
+	c.SetBit("testBits1", 10, 1)
+	c.SetBit("testBits2", 10, 1)
+
+	if bit, _ := c.GetBit("testBits1", 3); bit != 1 {
+		t.Errorf("Expected bit is 1, but found %v", bit)
+	}
+	if n, _ := c.BitCount("testBits1", 0, -1); n != 2 {
+		t.Errorf("Expected 2 set bits, but found %v", n)
+	}
+	if pos, _ := c.BitPos("testBits1", 1, 1, -1); pos != 10 {
+		t.Errorf("Expected position is 10, but found %v", pos)
+	}
+
+	n, err := c.BitOp(client.BitAnd, "testBits3", "testBits1", "testBits2")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 2 {
+		t.Errorf("Expected length is 2, but found %v", n)
+	}
+	if n, _ := c.BitCount("testBits3", 0, -1); n != 1 {
+		t.Errorf("Expected 1 set bit, but found %v", n)
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testVisitors1")
+	c.Delete("testVisitors2")
+	c.Delete("testVisitors3")
+	changed, err := c.PFAdd("testVisitors1", "alice", "bob", "alice")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if !changed {
+		t.Errorf("Expected the estimate to be changed")
+	}
+	c.PFAdd("testVisitors2", "bob", "carol")
+
+	n, err := c.PFCount("testVisitors1")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 2 {
+		t.Errorf("Expected count is 2, but found %v", n)
+	}
+	if n, _ := c.PFCount("testVisitors1", "testVisitors2"); n != 3 {
+		t.Errorf("Expected count of the union is 3, but found %v", n)
+	}
+
+	if err := c.PFMerge("testVisitors3", "testVisitors1", "testVisitors2"); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n, _ := c.PFCount("testVisitors3"); n != 3 {
+		t.Errorf("Expected count is 3, but found %v", n)
+	}
+}
+
+func TestGeo(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testSicily")
+	n, err := c.GeoAdd("testSicily",
+		client.GeoLocation{Member: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+		client.GeoLocation{Member: "Catania", Longitude: 15.087269, Latitude: 37.502669})
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 added members, but found %v", n)
+	}
+
+	pos, err := c.GeoPos("testSicily", "Palermo")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if math.Abs(pos.Longitude-13.361389) > 1e-5 || math.Abs(pos.Latitude-38.115556) > 1e-5 {
+		t.Errorf("Expected position is 13.361389,38.115556, but found %v,%v", pos.Longitude, pos.Latitude)
+	}
+
+	dist, err := c.GeoDist("testSicily", "Palermo", "Catania")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if math.Abs(dist-166274.15) > 1 {
+		t.Errorf("Expected distance is 166274.15, but found %v", dist)
+	}
+
+	found, err := c.GeoSearch("testSicily", client.GeoQuery{Longitude: 15, Latitude: 37, Radius: 100000})
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(found) != 1 || found[0].Member != "Catania" {
+		t.Errorf("Expected Catania, but found %v", found)
+	}
+	found, _ = c.GeoSearch("testSicily", client.GeoQuery{Member: "Palermo", Width: 400000, Height: 400000})
+	if len(found) != 2 || found[0].Member != "Palermo" {
+		t.Errorf("Expected Palermo and Catania, but found %v", found)
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testSeen")
+	if err := c.BFReserve("testSeen", 0.001, 1000); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	added, err := c.BFAdd("testSeen", "alice", "bob", "alice")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(added) != 3 || !added[0] || !added[1] || added[2] {
+		t.Errorf("Expected alice and bob to be added once, but found %v", added)
+	}
+
+	found, err := c.BFExists("testSeen", "alice", "carol")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(found) != 2 || !found[0] || found[1] {
+		t.Errorf("Expected only alice to be found, but found %v", found)
+	}
+
+	c.Delete("testSeenToo")
+	c.Delete("testSeenAll")
+	c.BFReserve("testSeenToo", 0.001, 1000)
+	c.BFAdd("testSeenToo", "carol")
+	if err := c.BFMerge("testSeenAll", "testSeen", "testSeenToo"); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if found, _ := c.BFExists("testSeenAll", "alice", "carol"); len(found) != 2 || !found[0] || !found[1] {
+		t.Errorf("Expected alice and carol to be found, but found %v", found)
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	c.Delete("testHits")
+	if err := c.CMSInit("testHits", 0.001, 0.01); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	counts, err := c.CMSAdd("testHits", "alice", "bob", "alice")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(counts) != 3 || counts[2] != 2 {
+		t.Errorf("Expected counts are [1 1 2], but found %v", counts)
+	}
+
+	n, err := c.CMSIncrBy("testHits", "bob", 10)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if n != 11 {
+		t.Errorf("Expected count is 11, but found %v", n)
+	}
+	if counts, _ := c.CMSQuery("testHits", "alice", "bob", "carol"); len(counts) != 3 || counts[0] != 2 || counts[1] != 11 || counts[2] != 0 {
+		t.Errorf("Expected counts are [2 11 0], but found %v", counts)
+	}
+
+	c.Delete("testHitsAll")
+	if err := c.CMSMerge("testHitsAll", "testHits", "testHits"); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if counts, _ := c.CMSQuery("testHitsAll", "bob"); len(counts) != 1 || counts[0] != 22 {
+		t.Errorf("Expected count is 22, but found %v", counts)
+	}
+}
+
+type user struct {
+	Name  string   `json:"name"`
+	Age   int      `json:"age"`
+	Roles []string `json:"roles"`
+}
+
+func TestTyped(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+	users := client.NewTyped[user](c)
+
+	err := users.Set("testAlice", user{Name: "Alice", Age: 30, Roles: []string{"admin"}}, time.Minute)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	u, err := users.Get("testAlice")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if u.Name != "Alice" || u.Age != 30 || len(u.Roles) != 1 || u.Roles[0] != "admin" {
+		t.Errorf("Expected Alice of 30 with admin role, but found %v", u)
+	}
+	if err = users.Update("testAlice", user{Name: "Alice", Age: 31}, time.Minute); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if u, _ = users.Get("testAlice"); u.Age != 31 {
+		t.Errorf("Expected age 31, but found %v", u.Age)
+	}
+
+	c.Set("testKey", "some_string_value", time.Minute)
+	_, err = client.NewTyped[int](c).Get("testKey")
+	var wrongType *client.WrongTypeError
+	if !errors.As(err, &wrongType) {
+		t.Errorf("Expected WrongTypeError, but found %v", err)
+	}
+	expected := "wrong type for key 'testKey': expected int, found string"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	for n := 0; n < 25; n++ {
+		c.Set(fmt.Sprintf("testScan:%02d", n), n, time.Minute)
+	}
+
+	var found []string
+	it := c.Scan("testScan:*", 10)
+	for it.Next() {
+		found = append(found, it.Key())
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(found) != 25 || found[0] != "testScan:00" || found[24] != "testScan:24" {
+		t.Errorf("Expected testScan:00 to testScan:24, but found %v", found)
+	}
+
+	keys, cursor, err := c.ScanPage("", "testScan:1*", 5)
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(keys) != 5 || keys[0] != "testScan:10" || cursor != "testScan:14" {
+		t.Errorf("Expected testScan:10 to testScan:14, but found %v and cursor %v", keys, cursor)
+	}
+
+	keys, err = c.Keys("testScan:2?")
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if len(keys) != 5 {
+		t.Errorf("Expected 5 keys, but found %v", keys)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+	team := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.InNamespace("testTeam"),
+		client.LogLatency())
+
+	c.DropNamespace("testTeam")
+	if err := c.CreateNamespace(client.Namespace{Name: "testTeam", Ttl: time.Minute}); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	namespaces, err := c.Namespaces()
+	if err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	found := false
+	for _, ns := range namespaces {
+		found = found || ns.Name == "testTeam" && ns.Ttl == time.Minute
+	}
+	if !found {
+		t.Errorf("Expected testTeam with ttl of a minute, but found %v", namespaces)
+	}
+
+	c.Delete("testNsKey")
+	if err := team.Set("testNsKey", "team_value", client.NoExpiration); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if val, _ := team.Get("testNsKey"); val != "team_value" {
+		t.Errorf("Expected value is team_value, but found %v", val)
+	}
+	if _, err := c.Get("testNsKey"); err == nil {
+		t.Errorf("Expected the key not to be in the default namespace")
+	}
+	if ttl, _ := team.TTL("testNsKey"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected default ttl of a minute, but found %v", ttl)
+	}
+
+	if err := c.DropNamespace("testTeam"); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	_, err = team.Get("testNsKey")
+	expected := "namespace 'testTeam' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestNamespace_DropBlockedRead(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"))
+	team := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.InNamespace("testBlocked"))
+
+	c.DropNamespace("testBlocked")
+	if err := c.CreateNamespace(client.Namespace{Name: "testBlocked"}); err != nil {
+		t.Fatalf("Error found: %v", err.Error())
+	}
+	go team.XRead("testBlockedStream", client.NewEntriesID, 0, 5*time.Second)
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	if err := c.DropNamespace("testBlocked"); err != nil {
+		t.Errorf("Error found: %v", err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected drop not to wait for the blocked read, but it took %v", elapsed)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := c.Watch(ctx, "testWatch:")
+	time.Sleep(time.Millisecond * 200) // to connect
+
+	c.Set("testWatch:1", "some_value", time.Minute)
+	c.Set("testOtherKey", "ignored", time.Minute)
+	c.Update("testWatch:1", "other_value", time.Minute)
+	c.Delete("testWatch:1")
+
+	expected := []client.Event{
+		{Type: client.EventSet, Key: "testWatch:1", Value: "some_value"},
+		{Type: client.EventUpdate, Key: "testWatch:1", Value: "other_value"},
+		{Type: client.EventDelete, Key: "testWatch:1"},
+	}
+	var seq uint64
+	for _, e := range expected {
+		select {
+		case found := <-events:
+			if found.Type != e.Type || found.Key != e.Key || found.Value != e.Value || found.Seq <= seq {
+				t.Errorf("Expected event is %v, but found %v", e, found)
+			}
+			seq = found.Seq
+		case <-time.After(time.Second * 5):
+			t.Fatalf("Expected event is %v, but found none", e)
+		}
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.LogLatency())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := c.Subscribe(ctx, "testNews")
+	if err != nil {
+		t.Fatalf("Error found: %v", err.Error())
+	}
+	if n, err := sub.PSubscribe("testNews.*"); err != nil || n != 2 {
+		t.Errorf("Expected 2 subscriptions, but found %v, %v", n, err)
+	}
+
+	if n, err := c.Publish("testNews.tech", "gopher"); err != nil || n != 1 {
+		t.Errorf("Expected 1 receiver, but found %v, %v", n, err)
+	}
+	if n, err := c.Publish("testNews", 42); err != nil || n != 1 {
+		t.Errorf("Expected 1 receiver, but found %v, %v", n, err)
+	}
+	expected := []client.Message{
+		{Channel: "testNews.tech", Pattern: "testNews.*", Payload: "gopher"},
+		{Channel: "testNews", Payload: float64(42)},
+	}
+	for _, m := range expected {
+		select {
+		case found := <-sub.C:
+			if found != m {
+				t.Errorf("Expected message is %v, but found %v", m, found)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("Expected message is %v, but found none", m)
+		}
+	}
+
+	if n, err := sub.Unsubscribe(); err != nil || n != 1 {
+		t.Errorf("Expected 1 subscription left, but found %v, %v", n, err)
+	}
+	cancel()
+	for range sub.C {
+	}
+	if err := sub.Err(); err != nil {
+		t.Errorf("Expected no error after close, but found %v", err)
+	}
+	if _, err := sub.Subscribe("testNews"); err == nil {
+		t.Errorf("Expected error of a closed subscription")
+	}
+
+	team := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"),
+		client.InNamespace("testMissingTeam"))
+	if _, err := team.Subscribe(context.Background(), "testNews"); err == nil {
+		t.Errorf("Expected error of a missing namespace")
+	}
+}
+
+func TestPubSub_NotRead(t *testing.T) {
+	c := client.New("http://localhost:8080/",
+		client.BasicAuthorization("username", "password"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := c.Subscribe(ctx, "testUnread")
+	if err != nil {
+		t.Fatalf("Error found: %v", err.Error())
+	}
+	for i := 0; i < 1100; i++ { // more than C holds
+		c.Publish("testUnread", i)
+	}
+
+	errs := make(chan error)
+	go func() {
+		_, err := sub.Subscribe("testUnreadToo")
+		errs <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Errorf("Expected error of a closed subscription")
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Expected Subscribe to stop once the subscription is closed")
+	}
+	for range sub.C {
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	c := client.New("http://localhost:1/") // nothing listens there
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 10)
+	events := c.WatchErrors(ctx, "testWatch:", func(err error, retry time.Duration) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Errorf("Expected error of the connection, but found nil")
+		}
+	case <-time.After(time.Second * 5):
+		t.Errorf("Expected error of the connection, but found none")
+	}
+
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+)
+
+const geoPath = "geo/"
+
+type GeoLocation struct {
+	Member    string  `json:"member"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+	Distance  float64 `json:"distance,omitempty"` // to the center of GeoSearch
+}
+
+type LocationsPayload struct {
+	Locations []GeoLocation `json:"locations"`
+}
+
+// center is the member if it is set, search is by radius if it is set and by box otherwise,
+// distances are in meters
+type GeoQuery struct {
+	Member    string
+	Longitude float64
+	Latitude  float64
+	Radius    float64
+	Width     float64
+	Height    float64
+	Count     int // 0 returns all found members
+}
+
+// returns number of members which were not in the index before, positions of the rest are updated
+func (c *Client) GeoAdd(key string, locations ...GeoLocation) (int, error) {
+	return intFromResponse(c.post(geoPath+key+"/add", LocationsPayload{Locations: locations}))
+}
+
+func (c *Client) GeoPos(key, member string) (GeoLocation, error) {
+	val, err := c.get(geoPath + key + "/members/" + url.PathEscape(member))
+	if err != nil {
+		return GeoLocation{}, err
+	}
+	return location(val), nil
+}
+
+// distance between members in meters
+func (c *Client) GeoDist(key, member1, member2 string) (float64, error) {
+	val, err := c.get(geoPath + key + "/dist?" + url.Values{"from": {member1}, "to": {member2}}.Encode())
+	if err != nil {
+		return 0, err
+	}
+	return val.(float64), nil
+}
+
+// members within the area of the query, the nearest first
+func (c *Client) GeoSearch(key string, q GeoQuery) ([]GeoLocation, error) {
+	query := url.Values{}
+	if q.Member != "" {
+		query.Set("member", q.Member)
+	} else {
+		query.Set("longitude", strconv.FormatFloat(q.Longitude, 'f', -1, 64))
+		query.Set("latitude", strconv.FormatFloat(q.Latitude, 'f', -1, 64))
+	}
+	if q.Radius > 0 {
+		query.Set("radius", strconv.FormatFloat(q.Radius, 'f', -1, 64))
+	} else {
+		query.Set("width", strconv.FormatFloat(q.Width, 'f', -1, 64))
+		query.Set("height", strconv.FormatFloat(q.Height, 'f', -1, 64))
+	}
+	if q.Count > 0 {
+		query.Set("count", strconv.Itoa(q.Count))
+	}
+
+	val, err := c.get(geoPath + key + "/search?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	result := make([]GeoLocation, len(values))
+	for n, v := range values {
+		result[n] = location(v)
+	}
+	return result, nil
+}
+
+func location(val interface{}) GeoLocation {
+	l := val.(map[string]interface{})
+	dist, _ := l["distance"].(float64)
+	return GeoLocation{
+		Member:    l["member"].(string),
+		Longitude: l["longitude"].(float64),
+		Latitude:  l["latitude"].(float64),
+		Distance:  dist,
+	}
+}
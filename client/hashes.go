@@ -0,0 +1,99 @@
+package client
+
+import (
+	"net/url"
+	"time"
+)
+
+const hashesPath = "hashes/"
+
+type FieldPayload struct {
+	Value interface{}   `json:"value"`
+	Ttl   time.Duration `json:"ttl"`
+}
+
+func fieldPath(key, field string) string {
+	return hashesPath + key + "/" + url.PathEscape(field)
+}
+
+// returns true if the field is new, expiration of the field is removed
+func (c *Client) HSet(key, field string, value interface{}) (bool, error) {
+	return c.HSetEx(key, field, value, NoExpiration)
+}
+
+// sets the field along with its own ttl, returns true if the field is new
+func (c *Client) HSetEx(key, field string, value interface{}, ttl time.Duration) (bool, error) {
+	payload, err := encode(FieldPayload{Value: value, Ttl: ttl})
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.request("PUT", fieldPath(key, field), payload)
+	if err != nil {
+		return false, err
+	}
+	val, err := getValueFromResponse(resp)
+	if err != nil {
+		return false, err
+	}
+	return val.(bool), nil
+}
+
+func (c *Client) HGet(key, field string) (interface{}, error) {
+	return c.get(fieldPath(key, field))
+}
+
+// returns true if the field was removed
+func (c *Client) HDel(key, field string) (bool, error) {
+	resp, err := c.request("DELETE", fieldPath(key, field), nil)
+	if err != nil {
+		return false, err
+	}
+	val, err := getValueFromResponse(resp)
+	if err != nil {
+		return false, err
+	}
+	return val.(float64) > 0, nil
+}
+
+func (c *Client) HGetAll(key string) (map[string]interface{}, error) {
+	val, err := c.get(hashesPath + key)
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := val.(map[string]interface{})
+	return fields, nil
+}
+
+func (c *Client) HIncrBy(key, field string, delta int64) (int64, error) {
+	return c.postInt(fieldPath(key, field)+"/incr", IncrPayload{Delta: delta})
+}
+
+// fields in sorted order
+func (c *Client) HKeys(key string) ([]string, error) {
+	return c.strings(hashesPath + key + "?view=keys")
+}
+
+func (c *Client) HLen(key string) (int, error) {
+	val, err := c.get(hashesPath + key + "?view=len")
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+// remaining time to live of the field, NoExpiration for persistent fields
+func (c *Client) HTTL(key, field string) (time.Duration, error) {
+	return c.ttl(fieldPath(key, field) + "/ttl")
+}
+
+func (c *Client) HExpire(key, field string, ttl time.Duration) error {
+	return c.setExpiration(fieldPath(key, field)+"/ttl", TtlPayload{Ttl: ttl})
+}
+
+func (c *Client) HExpireAt(key, field string, t time.Time) error {
+	return c.setExpiration(fieldPath(key, field)+"/ttl", TtlPayload{ExpireAt: t})
+}
+
+func (c *Client) HPersist(key, field string) error {
+	return c.persist(fieldPath(key, field) + "/ttl")
+}
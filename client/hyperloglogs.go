@@ -0,0 +1,34 @@
+package client
+
+const hyperLogLogsPath = "hyperloglogs/"
+
+type ElementsPayload struct {
+	Elements []string `json:"elements"`
+}
+
+type KeysPayload struct {
+	Keys []string `json:"keys"`
+}
+
+// returns true if the estimated number of elements was changed
+func (c *Client) PFAdd(key string, elements ...string) (bool, error) {
+	val, err := c.post(hyperLogLogsPath+key+"/add", ElementsPayload{Elements: elements})
+	if err != nil {
+		return false, err
+	}
+	return val.(bool), nil
+}
+
+// estimated number of unique elements added to any of the keys
+func (c *Client) PFCount(key string, others ...string) (uint64, error) {
+	val, err := c.get(hyperLogLogsPath + key + "/count?" + withQuery(others))
+	if err != nil {
+		return 0, err
+	}
+	return uint64(val.(float64)), nil
+}
+
+func (c *Client) PFMerge(dest string, keys ...string) error {
+	_, err := c.post(hyperLogLogsPath+dest+"/merge", KeysPayload{Keys: keys})
+	return err
+}
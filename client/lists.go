@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+)
+
+const listsPath = "lists/"
+
+type ValuesPayload struct {
+	Values []interface{} `json:"values"`
+}
+
+type RangePayload struct {
+	Start int `json:"start"`
+	Stop  int `json:"stop"`
+}
+
+// returns length of the list after the push
+func (c *Client) LPush(key string, values ...interface{}) (int, error) {
+	return c.push(key, "/lpush", values)
+}
+
+// returns length of the list after the push
+func (c *Client) RPush(key string, values ...interface{}) (int, error) {
+	return c.push(key, "/rpush", values)
+}
+
+func (c *Client) push(key string, op string, values []interface{}) (int, error) {
+	val, err := c.post(listsPath+key+op, ValuesPayload{Values: values})
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+func (c *Client) LPop(key string) (interface{}, error) {
+	return c.post(listsPath+key+"/lpop", nil)
+}
+
+func (c *Client) RPop(key string) (interface{}, error) {
+	return c.post(listsPath+key+"/rpop", nil)
+}
+
+// indexes are inclusive, negative ones count from the end
+func (c *Client) LRange(key string, start, stop int) ([]interface{}, error) {
+	val, err := c.get(fmt.Sprintf("%s%s?start=%d&stop=%d", listsPath, key, start, stop))
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	return values, nil
+}
+
+func (c *Client) LLen(key string) (int, error) {
+	val, err := c.get(listsPath + key + "/len")
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+func (c *Client) LTrim(key string, start, stop int) error {
+	_, err := c.post(listsPath+key+"/trim", RangePayload{Start: start, Stop: stop})
+	return err
+}
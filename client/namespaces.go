@@ -0,0 +1,46 @@
+package client
+
+import "time"
+
+const namespacesPath = adminPath + "namespaces"
+
+type Namespace struct {
+	Name     string        `json:"name"`
+	Ttl      time.Duration `json:"ttl"`       // of keys set without ttl, 0 keeps them until deleted
+	MaxItems int           `json:"max_items"` // 0 for no limit
+	MaxBytes int64         `json:"max_bytes"` // 0 for no limit
+}
+
+func (c *Client) Namespaces() ([]Namespace, error) {
+	val, err := c.get(namespacesPath)
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	result := make([]Namespace, len(values))
+	for n, v := range values {
+		ns := v.(map[string]interface{})
+		result[n] = Namespace{
+			Name:     ns["name"].(string),
+			Ttl:      time.Duration(ns["ttl"].(float64)),
+			MaxItems: int(ns["max_items"].(float64)),
+			MaxBytes: int64(ns["max_bytes"].(float64)),
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) CreateNamespace(ns Namespace) error {
+	_, err := c.post(namespacesPath+"/"+ns.Name, ns)
+	return err
+}
+
+// removes the namespace with all its keys
+func (c *Client) DropNamespace(name string) error {
+	resp, err := c.request("DELETE", namespacesPath+"/"+name, nil)
+	if err != nil {
+		return err
+	}
+	_, err = getValueFromResponse(resp)
+	return err
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscriptions are websockets which get messages published to their channels or to channels
+// matching their patterns, messages are not stored, so a subscription gets only those published
+// after its reply, C should be read all the time or the server closes the subscription
+//	sub, err := c.PSubscribe(ctx, "news.*")
+//	for m := range sub.C {
+//		fmt.Println(m.Channel, m.Payload)
+//	}
+
+const (
+	channelsPath   = "channels/"
+	pubsubPath     = "pubsub"
+	frameMessage   = "message"
+	frameError     = "error"
+	messagesBuffer = 1024
+	replyTimeout   = time.Second * 10
+	connKey        = contextKey("conn")
+)
+
+var (
+	errSubscriptionClosed = errors.New("subscription is closed")
+	errReplyTimeout       = errors.New("no reply in time, C is probably not read")
+)
+
+type contextKey string
+
+type PublishPayload struct {
+	Payload interface{} `json:"payload"`
+}
+
+type PubSubOp struct {
+	Op       string   `json:"op"`
+	Channels []string `json:"channels"`
+}
+
+type PubSubFrame struct {
+	Type    string      `json:"type"`
+	Channel string      `json:"channel"`
+	Pattern string      `json:"pattern"`
+	Payload interface{} `json:"payload"`
+	Count   int         `json:"count"`
+	Error   string      `json:"error"`
+}
+
+type Message struct {
+	Channel string
+	Pattern string // which matched the channel, "" for a subscription to the channel
+	Payload interface{}
+}
+
+type Subscription struct {
+	C         <-chan Message // closed once the subscription is over
+	c         chan Message
+	conn      *websocket.Conn
+	mu        sync.Mutex // one op at a time
+	replies   chan PubSubFrame
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// returns number of subscriptions which got the message
+func (c *Client) Publish(channel string, payload interface{}) (int, error) {
+	val, err := c.post(channelsPath+channel+"/publish", PublishPayload{Payload: payload})
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+// a subscription to nothing yet, it lasts until Close or the context is done
+func (c *Client) NewSubscription(ctx context.Context) (*Subscription, error) {
+	req, err := c.newRequest("GET", pubsubPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	var conn *websocket.Conn
+	resp, err := c.dialClient.Do(req.WithContext(context.WithValue(ctx, connKey, &conn)))
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		_, err = getValueFromResponse(resp)
+		return nil, fmt.Errorf("unexpected status %v: %v", resp.Status, err)
+	}
+
+	messages := make(chan Message, messagesBuffer)
+	sub := &Subscription{
+		C:       messages,
+		c:       messages,
+		conn:    conn,
+		replies: make(chan PubSubFrame, 1),
+		closed:  make(chan struct{}),
+	}
+	go sub.read()
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+		case <-sub.closed:
+		}
+	}()
+	return sub, nil
+}
+
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (*Subscription, error) {
+	sub, err := c.NewSubscription(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = sub.Subscribe(channels...); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// patterns are globs like news.*
+func (c *Client) PSubscribe(ctx context.Context, patterns ...string) (*Subscription, error) {
+	sub, err := c.NewSubscription(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = sub.PSubscribe(patterns...); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// returns number of channels and patterns the subscription has
+func (sub *Subscription) Subscribe(channels ...string) (int, error) {
+	return sub.op("subscribe", channels)
+}
+
+// returns number of channels and patterns the subscription has
+func (sub *Subscription) PSubscribe(patterns ...string) (int, error) {
+	return sub.op("psubscribe", patterns)
+}
+
+// no channels unsubscribes from all of them, returns number of channels and patterns left
+func (sub *Subscription) Unsubscribe(channels ...string) (int, error) {
+	return sub.op("unsubscribe", channels)
+}
+
+// no patterns unsubscribes from all of them, returns number of channels and patterns left
+func (sub *Subscription) PUnsubscribe(patterns ...string) (int, error) {
+	return sub.op("punsubscribe", patterns)
+}
+
+// the reply comes after messages which were sent before it, so C should be read meanwhile,
+// otherwise the reply doesn't come in time and the subscription is closed, as a late reply
+// would be taken for the reply of the next op
+func (sub *Subscription) op(op string, channels []string) (int, error) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if err := sub.conn.WriteJSON(PubSubOp{Op: op, Channels: channels}); err != nil {
+		return 0, err
+	}
+	timer := time.NewTimer(replyTimeout)
+	defer timer.Stop()
+	var reply PubSubFrame
+	var ok bool
+	select {
+	case reply, ok = <-sub.replies:
+		if !ok {
+			return 0, errSubscriptionClosed
+		}
+	case <-sub.closed:
+		return 0, errSubscriptionClosed
+	case <-timer.C:
+		sub.Close()
+		return 0, errReplyTimeout
+	}
+	if reply.Type == frameError {
+		return 0, errors.New(reply.Error)
+	}
+	return reply.Count, nil
+}
+
+func (sub *Subscription) Close() error {
+	var err error
+	sub.closeOnce.Do(func() {
+		close(sub.closed)
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		// WriteControl may run along with WriteJSON of op, which holds sub.mu while it waits for the reply
+		sub.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		err = sub.conn.Close()
+	})
+	return err
+}
+
+// why C was closed, nil after Close, the server tells its reason in *websocket.CloseError,
+// should be called once C is closed
+func (sub *Subscription) Err() error {
+	return sub.err
+}
+
+// sends wait for sub.closed as well, so nothing is left blocked once the subscription is closed
+// with C not being read
+func (sub *Subscription) read() {
+	var err error
+	for {
+		var frame PubSubFrame
+		if err = sub.conn.ReadJSON(&frame); err != nil {
+			break
+		}
+		switch frame.Type {
+		case frameMessage:
+			select {
+			case sub.c <- Message{Channel: frame.Channel, Pattern: frame.Pattern, Payload: frame.Payload}:
+				continue
+			case <-sub.closed:
+			}
+		default:
+			select {
+			case sub.replies <- frame:
+				continue
+			case <-sub.closed:
+			}
+		}
+		break
+	}
+	select {
+	case <-sub.closed:
+		sub.err = nil
+	default:
+		sub.err = err
+		sub.conn.Close()
+	}
+	close(sub.replies)
+	close(sub.c)
+}
+
+// the last of the middlewares, dials the websocket of the request and puts it to the context,
+// so requests to websockets have the same authorization and namespace as the rest
+func dialWebsocket(r *http.Request) (*http.Response, error) {
+	u := *r.URL
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	conn, resp, err := websocket.DefaultDialer.DialContext(r.Context(), u.String(), r.Header)
+	if err == websocket.ErrBadHandshake {
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	*r.Context().Value(connKey).(**websocket.Conn) = conn
+	return resp, nil
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+)
+
+const scanPath = "scan"
+
+// iterates over keys page by page, keys are in order and every key which exists during
+// the whole scan is returned once
+//	it := c.Scan("user:*", 100)
+//	for it.Next() {
+//		fmt.Println(it.Key())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+
+type KeyIterator struct {
+	c      *Client
+	match  string
+	count  int
+	cursor string
+	keys   []string
+	key    string
+	done   bool
+	err    error
+}
+
+// count is a number of keys checked per request, 0 takes the default of the server
+func (c *Client) Scan(match string, count int) *KeyIterator {
+	return &KeyIterator{c: c, match: match, count: count}
+}
+
+// moves to the next key, requests the next page once the current one is over
+func (it *KeyIterator) Next() bool {
+	for len(it.keys) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.keys, it.cursor, it.err = it.c.ScanPage(it.cursor, it.match, it.count)
+		it.done = it.cursor == ""
+	}
+	it.key, it.keys = it.keys[0], it.keys[1:]
+	return true
+}
+
+func (it *KeyIterator) Key() string {
+	return it.key
+}
+
+func (it *KeyIterator) Err() error {
+	return it.err
+}
+
+// returns keys of a single page and the cursor of the next one, it is "" once the scan is over
+func (c *Client) ScanPage(cursor, match string, count int) ([]string, string, error) {
+	query := url.Values{"cursor": {cursor}, "match": {match}}
+	if count > 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+	val, err := c.get(scanPath + "?" + query.Encode())
+	if err != nil {
+		return nil, "", err
+	}
+	page := val.(map[string]interface{})
+	keys, _ := page["keys"].([]interface{})
+	result := make([]string, len(keys))
+	for n, k := range keys {
+		result[n] = k.(string)
+	}
+	return result, page["cursor"].(string), nil
+}
+
+// all keys which match the glob pattern, "" for all keys
+func (c *Client) Keys(match string) ([]string, error) {
+	return c.strings("keys?" + url.Values{"match": {match}}.Encode())
+}
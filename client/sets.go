@@ -0,0 +1,79 @@
+package client
+
+import (
+	"net/url"
+)
+
+const setsPath = "sets/"
+
+type MembersPayload struct {
+	Members []string `json:"members"`
+}
+
+// returns number of members which were not in the set before
+func (c *Client) SAdd(key string, members ...string) (int, error) {
+	return c.members(setsPath, key, "/add", members)
+}
+
+// returns number of members which were removed
+func (c *Client) SRem(key string, members ...string) (int, error) {
+	return c.members(setsPath, key, "/rem", members)
+}
+
+func (c *Client) members(path, key, op string, members []string) (int, error) {
+	val, err := c.post(path+key+op, MembersPayload{Members: members})
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+func (c *Client) SIsMember(key, member string) (bool, error) {
+	val, err := c.get(setsPath + key + "/members/" + url.PathEscape(member))
+	if err != nil {
+		return false, err
+	}
+	return val.(bool), nil
+}
+
+// members in sorted order
+func (c *Client) SMembers(key string) ([]string, error) {
+	return c.strings(setsPath + key)
+}
+
+func (c *Client) SCard(key string) (int, error) {
+	val, err := c.get(setsPath + key + "/card")
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+func (c *Client) SUnion(key string, others ...string) ([]string, error) {
+	return c.strings(setsPath + key + "/union?" + withQuery(others))
+}
+
+func (c *Client) SInter(key string, others ...string) ([]string, error) {
+	return c.strings(setsPath + key + "/inter?" + withQuery(others))
+}
+
+func (c *Client) SDiff(key string, others ...string) ([]string, error) {
+	return c.strings(setsPath + key + "/diff?" + withQuery(others))
+}
+
+func withQuery(keys []string) string {
+	return url.Values{"with": keys}.Encode()
+}
+
+func (c *Client) strings(path string) ([]string, error) {
+	val, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	result := make([]string, len(values))
+	for n, v := range values {
+		result[n] = v.(string)
+	}
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package client
+
+import "net/url"
+
+const sketchesPath = "sketches/"
+
+type SketchPayload struct {
+	ErrorRate   float64 `json:"error_rate"`
+	Probability float64 `json:"probability"`
+}
+
+// creates an empty sketch with the given parameters, the key must not exist
+func (c *Client) CMSInit(key string, errorRate, probability float64) error {
+	_, err := c.post(sketchesPath+key+"/init", SketchPayload{ErrorRate: errorRate, Probability: probability})
+	return err
+}
+
+// counts each of the elements once and returns their estimated counts
+func (c *Client) CMSAdd(key string, elements ...string) ([]uint64, error) {
+	return counts(c.post(sketchesPath+key+"/add", ElementsPayload{Elements: elements}))
+}
+
+// returns estimated count of the element after the increment
+func (c *Client) CMSIncrBy(key, element string, delta uint64) (uint64, error) {
+	val, err := c.post(sketchesPath+key+"/elements/"+url.PathEscape(element)+"/incr", IncrPayload{Delta: int64(delta)})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(val.(float64)), nil
+}
+
+func (c *Client) CMSQuery(key string, elements ...string) ([]uint64, error) {
+	return counts(c.get(sketchesPath + key + "/query?" + url.Values{"element": elements}.Encode()))
+}
+
+// all of the keys must have the same error rate and probability
+func (c *Client) CMSMerge(dest string, keys ...string) error {
+	_, err := c.post(sketchesPath+dest+"/merge", KeysPayload{Keys: keys})
+	return err
+}
+
+func counts(val interface{}, err error) ([]uint64, error) {
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	result := make([]uint64, len(values))
+	for n, v := range values {
+		result[n] = uint64(v.(float64))
+	}
+	return result, nil
+}
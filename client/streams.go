@@ -0,0 +1,162 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	streamsPath = "streams/"
+
+	NoMaxLen     = 0   // keeps all entries of a stream
+	FirstID      = "-" // the first possible id of a stream
+	LastID       = "+" // the last possible id of a stream
+	NewEntriesID = "$" // entries added after the call
+)
+
+type StreamEntry struct {
+	ID     string                 `json:"id"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type PendingEntry struct {
+	ID         string    `json:"id"`
+	Consumer   string    `json:"consumer"`
+	Delivered  time.Time `json:"delivered"`
+	Deliveries int       `json:"deliveries"`
+}
+
+type EntryPayload struct {
+	Fields map[string]interface{} `json:"fields"`
+	MaxLen int                    `json:"maxlen"`
+}
+
+type TrimPayload struct {
+	MaxLen int `json:"maxlen"`
+}
+
+type GroupPayload struct {
+	Start string `json:"start"`
+}
+
+type ReadGroupPayload struct {
+	Consumer string        `json:"consumer"`
+	Count    int           `json:"count"`
+	Block    time.Duration `json:"block"`
+}
+
+type AckPayload struct {
+	IDs []string `json:"ids"`
+}
+
+// returns id of the new entry, the oldest entries over maxLen are dropped
+func (c *Client) XAdd(key string, fields map[string]interface{}, maxLen int) (string, error) {
+	val, err := c.post(streamsPath+key+"/add", EntryPayload{Fields: fields, MaxLen: maxLen})
+	if err != nil {
+		return "", err
+	}
+	return val.(string), nil
+}
+
+// returns number of dropped entries
+func (c *Client) XTrim(key string, maxLen int) (int, error) {
+	val, err := c.post(streamsPath+key+"/trim", TrimPayload{MaxLen: maxLen})
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+func (c *Client) XLen(key string) (int, error) {
+	val, err := c.get(streamsPath + key + "/len")
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+// entries with ids from start to end inclusive, count 0 returns all of them
+func (c *Client) XRange(key, start, end string, count int) ([]StreamEntry, error) {
+	query := url.Values{
+		"start": {start},
+		"end":   {end},
+		"count": {strconv.Itoa(count)},
+	}
+	return c.entries(c.get(streamsPath + key + "?" + query.Encode()))
+}
+
+// entries with ids greater than after, waits up to block for new ones,
+// block should stay within the timeout of the client
+func (c *Client) XRead(key, after string, count int, block time.Duration) ([]StreamEntry, error) {
+	query := url.Values{
+		"after": {after},
+		"count": {strconv.Itoa(count)},
+		"block": {block.String()},
+	}
+	return c.entries(c.get(streamsPath + key + "/read?" + query.Encode()))
+}
+
+// start is NewEntriesID for entries added later or "0" for all of them
+func (c *Client) XGroupCreate(key, group, start string) error {
+	_, err := c.post(groupPath(key, group), GroupPayload{Start: start})
+	return err
+}
+
+// entries the group has not seen yet, they stay pending until XAck
+func (c *Client) XReadGroup(key, group, consumer string, count int, block time.Duration) ([]StreamEntry, error) {
+	payload := ReadGroupPayload{Consumer: consumer, Count: count, Block: block}
+	return c.entries(c.post(groupPath(key, group)+"/read", payload))
+}
+
+// returns number of entries which were pending
+func (c *Client) XAck(key, group string, ids ...string) (int, error) {
+	val, err := c.post(groupPath(key, group)+"/ack", AckPayload{IDs: ids})
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+// entries delivered to consumers of the group and not acked yet
+func (c *Client) XPending(key, group string) ([]PendingEntry, error) {
+	val, err := c.get(groupPath(key, group) + "/pending")
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	result := make([]PendingEntry, len(values))
+	for n, v := range values {
+		p := v.(map[string]interface{})
+		delivered, err := time.Parse(time.RFC3339Nano, p["delivered"].(string))
+		if err != nil {
+			return nil, fmt.Errorf("malformed delivery time %v", p["delivered"])
+		}
+		result[n] = PendingEntry{
+			ID:         p["id"].(string),
+			Consumer:   p["consumer"].(string),
+			Delivered:  delivered,
+			Deliveries: int(p["deliveries"].(float64)),
+		}
+	}
+	return result, nil
+}
+
+func groupPath(key, group string) string {
+	return streamsPath + key + "/groups/" + url.PathEscape(group)
+}
+
+func (c *Client) entries(val interface{}, err error) ([]StreamEntry, error) {
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	result := make([]StreamEntry, len(values))
+	for n, v := range values {
+		e := v.(map[string]interface{})
+		fields, _ := e["fields"].(map[string]interface{})
+		result[n] = StreamEntry{ID: e["id"].(string), Fields: fields}
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"time"
+)
+
+// builder of a transaction executed by the server all together or not at all
+//	results, err := c.Txn().
+//		Check("key1", version).
+//		Set("key1", "value", time.Minute).
+//		Delete("key2").
+//		Get("key3").
+//		Exec()
+
+type Txn struct {
+	c   *Client
+	ops []TxnOp
+}
+
+type TxnOp struct {
+	Op      string        `json:"op"`
+	Key     string        `json:"key"`
+	Value   interface{}   `json:"value,omitempty"`
+	Ttl     time.Duration `json:"ttl,omitempty"`
+	Version uint64        `json:"version,omitempty"`
+}
+
+type TxnPayload struct {
+	Ops []TxnOp `json:"ops"`
+}
+
+func (c *Client) Txn() *Txn {
+	return &Txn{c: c}
+}
+
+func (t *Txn) Get(key string) *Txn {
+	return t.add(TxnOp{Op: "get", Key: key})
+}
+
+func (t *Txn) Set(key string, value interface{}, ttl time.Duration) *Txn {
+	return t.add(TxnOp{Op: "set", Key: key, Value: value, Ttl: ttl})
+}
+
+func (t *Txn) Update(key string, value interface{}, ttl time.Duration) *Txn {
+	return t.add(TxnOp{Op: "update", Key: key, Value: value, Ttl: ttl})
+}
+
+func (t *Txn) Delete(key string) *Txn {
+	return t.add(TxnOp{Op: "delete", Key: key})
+}
+
+// version 0 stands for a key which doesn't exist
+func (t *Txn) Check(key string, version uint64) *Txn {
+	return t.add(TxnOp{Op: "check", Key: key, Version: version})
+}
+
+// returns a result per operation, values for gets and nil for the rest
+func (t *Txn) Exec() ([]interface{}, error) {
+	val, err := t.c.post(txnPath, TxnPayload{Ops: t.ops})
+	if err != nil {
+		return nil, err
+	}
+	results, _ := val.([]interface{})
+	return results, nil
+}
+
+func (t *Txn) add(op TxnOp) *Txn {
+	t.ops = append(t.ops, op)
+	return t
+}
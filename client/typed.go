@@ -0,0 +1,76 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Typed is a view of the keys with values of a single type, values go through json both ways
+//	users := client.NewTyped[User](c)
+//	err := users.Set("alice", User{Name: "Alice"}, time.Hour)
+//	u, err := users.Get("alice")
+
+type Typed[T any] struct {
+	c *Client
+}
+
+// returned by Typed when the value of the key doesn't decode into its type
+type WrongTypeError struct {
+	Key      string
+	Expected string
+	Found    string
+}
+
+func (e *WrongTypeError) Error() string {
+	return fmt.Sprintf("wrong type for key '%v': expected %v, found %v", e.Key, e.Expected, e.Found)
+}
+
+func NewTyped[T any](c *Client) *Typed[T] {
+	return &Typed[T]{c: c}
+}
+
+func (t *Typed[T]) Get(key string) (T, error) {
+	var result T
+	val, err := t.c.Get(key)
+	if err != nil {
+		return result, err
+	}
+	b, err := json.Marshal(val)
+	if err != nil {
+		return result, err
+	}
+	if err = json.Unmarshal(b, &result); err != nil {
+		return result, &WrongTypeError{
+			Key:      key,
+			Expected: reflect.TypeOf(&result).Elem().String(),
+			Found:    jsonType(val),
+		}
+	}
+	return result, nil
+}
+
+func (t *Typed[T]) Set(key string, value T, ttl time.Duration) error {
+	return t.c.Set(key, value, ttl)
+}
+
+func (t *Typed[T]) Update(key string, value T, ttl time.Duration) error {
+	return t.c.Update(key, value, ttl)
+}
+
+func jsonType(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Watch streams changes of keys until the context is done, the stream is resumed after the last
+// event once the connection is lost, if the server doesn't have the missed events anymore an
+// EventReset comes and the keys should be read again
+//	events := c.Watch(ctx, "user:")
+//	for e := range events {
+//		fmt.Println(e.Type, e.Key, e.Value)
+//	}
+
+const (
+	watchPath        = "watch"
+	watchIdleTimeout = time.Second * 45 // three heartbeats of the server
+	watchMinBackoff  = time.Millisecond * 100
+	watchMaxBackoff  = time.Second * 10
+
+	EventSet    = "set" // of a key which didn't exist
+	EventUpdate = "update"
+	EventDelete = "delete"
+	EventExpire = "expire"
+	EventEvict  = "evict"
+	EventReset  = "reset" // events were lost, keys should be read again
+	eventError  = "error"
+)
+
+type Event struct {
+	Seq        uint64      `json:"seq"`
+	Type       string      `json:"type"`
+	Key        string      `json:"key"`
+	OldVersion uint64      `json:"old_version"` // 0 for a key which didn't exist
+	Version    uint64      `json:"version"`     // 0 for a key which is gone
+	Value      interface{} `json:"value"`       // nil for a key which is gone and a container changed in place
+	Change     *Change     `json:"change"`
+}
+
+// a container changed in place, args are the fields of the change, as {"Values": ["a"], "Head": false} of listPush
+type Change struct {
+	Op   string                 `json:"op"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// the channel is closed once the context is done, errors of broken streams are not reported
+func (c *Client) Watch(ctx context.Context, prefix string) <-chan Event {
+	return c.WatchErrors(ctx, prefix, nil)
+}
+
+// as Watch, onError gets the error which broke the stream and the delay before it is resumed,
+// it's called from the goroutine which reads the stream, so it should not block
+func (c *Client) WatchErrors(ctx context.Context, prefix string, onError func(err error, retry time.Duration)) <-chan Event {
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		var last string
+		backoff := watchMinBackoff
+		for {
+			connected, err := c.watch(ctx, prefix, &last, events)
+			if ctx.Err() != nil {
+				return
+			}
+			if connected {
+				backoff = watchMinBackoff
+			}
+			if onError != nil {
+				onError(err, backoff)
+			}
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > watchMaxBackoff {
+				backoff = watchMaxBackoff
+			}
+		}
+	}()
+	return events
+}
+
+// reads a single stream until it breaks, last is the id of the last event sent
+func (c *Client) watch(ctx context.Context, prefix string, last *string, events chan<- Event) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := c.newRequest("GET", watchPath+"?"+url.Values{"prefix": {prefix}}.Encode(), nil)
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+	if *last != "" {
+		req.Header.Set("Last-Event-ID", *last)
+	}
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_, err = getValueFromResponse(resp)
+		return false, fmt.Errorf("unexpected status %v: %v", resp.Status, err)
+	}
+
+	// the server sends heartbeats, so silence means the connection is gone
+	idle := time.AfterFunc(watchIdleTimeout, cancel)
+	defer idle.Stop()
+
+	var id, event, data string
+	reader := bufio.NewReader(resp.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return true, err
+		}
+		idle.Reset(watchIdleTimeout)
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "":
+			if event == eventError {
+				return true, fmt.Errorf("stream is over: %v", data)
+			}
+			if event != "" {
+				e := Event{Type: event}
+				if event != EventReset {
+					if err = json.Unmarshal([]byte(data), &e); err != nil {
+						return true, err
+					}
+				}
+				select {
+				case events <- e:
+					if id != "" {
+						*last = id
+					}
+				case <-ctx.Done():
+					return true, ctx.Err()
+				}
+			}
+			id, event, data = "", "", ""
+		case strings.HasPrefix(line, ":"): // heartbeat
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const zsetsPath = "zsets/"
+
+type ScoredMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+type ScoredMembersPayload struct {
+	Members []ScoredMember `json:"members"`
+}
+
+// returns number of members which were not in the sorted set before, scores of the rest are updated
+func (c *Client) ZAdd(key string, members ...ScoredMember) (int, error) {
+	val, err := c.post(zsetsPath+key+"/add", ScoredMembersPayload{Members: members})
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+// returns number of members which were removed
+func (c *Client) ZRem(key string, members ...string) (int, error) {
+	return c.members(zsetsPath, key, "/rem", members)
+}
+
+func (c *Client) ZIncrBy(key, member string, delta float64) (float64, error) {
+	val, err := c.post(zsetsPath+key+"/members/"+url.PathEscape(member)+"/incr", IncrFloatPayload{Delta: delta})
+	if err != nil {
+		return 0, err
+	}
+	return val.(float64), nil
+}
+
+func (c *Client) ZScore(key, member string) (float64, error) {
+	val, err := c.get(zsetsPath + key + "/members/" + url.PathEscape(member))
+	if err != nil {
+		return 0, err
+	}
+	return val.(float64), nil
+}
+
+// 0 based position of the member in order of scores
+func (c *Client) ZRank(key, member string) (int, error) {
+	val, err := c.get(zsetsPath + key + "/members/" + url.PathEscape(member) + "/rank")
+	if err != nil {
+		return 0, err
+	}
+	return int(val.(float64)), nil
+}
+
+// min and max are inclusive
+func (c *Client) ZRangeByScore(key string, min, max float64) ([]ScoredMember, error) {
+	query := url.Values{
+		"min": {strconv.FormatFloat(min, 'g', -1, 64)},
+		"max": {strconv.FormatFloat(max, 'g', -1, 64)},
+	}
+	return c.scoredMembers(zsetsPath + key + "/byscore?" + query.Encode())
+}
+
+// start and stop are inclusive and can be negative, -1 is the member with the highest score
+func (c *Client) ZRangeByRank(key string, start, stop int) ([]ScoredMember, error) {
+	return c.scoredMembers(fmt.Sprintf("%s%s?start=%d&stop=%d", zsetsPath, key, start, stop))
+}
+
+func (c *Client) scoredMembers(path string) ([]ScoredMember, error) {
+	val, err := c.get(path)
+	if err != nil {
+		return nil, err
+	}
+	values, _ := val.([]interface{})
+	result := make([]ScoredMember, len(values))
+	for n, v := range values {
+		m := v.(map[string]interface{})
+		result[n] = ScoredMember{Member: m["member"].(string), Score: m["score"].(float64)}
+	}
+	return result, nil
+}
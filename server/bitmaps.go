@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+func registerBitmapRoutes(r *mux.Router) {
+	r.HandleFunc("/bitmaps/{key}/bits/{offset}", GetBitHandler).Methods("GET")
+	r.HandleFunc("/bitmaps/{key}/bits/{offset}", SetBitHandler).Methods("PUT")
+	r.HandleFunc("/bitmaps/{key}/count", BitCountHandler).Methods("GET")
+	r.HandleFunc("/bitmaps/{key}/pos", BitPosHandler).Methods("GET")
+	r.HandleFunc("/bitmaps/{key}/op", BitOpHandler).Methods("POST")
+}
+
+type BitPayload struct {
+	Bit int `json:"bit"`
+}
+
+type BitOpPayload struct {
+	Op   store.BitOperation `json:"op"`
+	Keys []string           `json:"keys"`
+}
+
+func GetBitHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	offset, err := parseOffset(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	bit, err := storeOf(r).GetBit(key, offset)
+
+	withWriter(w).
+		Data(bit).
+		Error(err).
+		WriteResponse()
+}
+
+// data is the bit which was at the offset before
+func SetBitHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	offset, err := parseOffset(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	var payload BitPayload
+	decodeBody(r, &payload)
+	old, err := storeOf(r).SetBit(key, offset, payload.Bit)
+
+	withWriter(w).
+		Data(old).
+		Error(err).
+		WriteResponse()
+}
+
+// ?start=0&end=-1 by default, in bytes
+func BitCountHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	start, end, err := parseByteRange(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	n, err := storeOf(r).BitCount(key, start, end)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+// ?bit=1&start=0&end=-1 by default
+func BitPosHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	bit, err := parseIntQuery(r, "bit", 1)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	start, end, err := parseByteRange(r)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	pos, err := storeOf(r).BitPos(key, bit, start, end)
+
+	withWriter(w).
+		Data(pos).
+		Error(err).
+		WriteResponse()
+}
+
+// the key of the path is the destination, data is length of the result
+func BitOpHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload BitOpPayload
+	decodeBody(r, &payload)
+	n, err := storeOf(r).BitOp(payload.Op, key, payload.Keys...)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func parseOffset(r *http.Request) (int, error) {
+	val := mux.Vars(r)["offset"]
+	offset, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("malformed offset %v", val)
+	}
+	return offset, nil
+}
+
+func parseByteRange(r *http.Request) (int, int, error) {
+	start, err := parseIntQuery(r, "start", 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseIntQuery(r, "end", -1)
+	return start, end, err
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func registerBloomRoutes(r *mux.Router) {
+	r.HandleFunc("/blooms/{key}/reserve", BFReserveHandler).Methods("POST")
+	r.HandleFunc("/blooms/{key}/add", BFAddHandler).Methods("POST")
+	r.HandleFunc("/blooms/{key}/exists", BFExistsHandler).Methods("GET")
+	r.HandleFunc("/blooms/{key}/merge", BFMergeHandler).Methods("POST")
+}
+
+type BloomPayload struct {
+	ErrorRate float64 `json:"error_rate"`
+	Capacity  int     `json:"capacity"`
+}
+
+func BFReserveHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload BloomPayload
+	decodeBody(r, &payload)
+	err := storeOf(r).BFReserve(key, payload.ErrorRate, payload.Capacity)
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
+
+// data is true for elements which were definitely not added before
+func BFAddHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload ElementsPayload
+	decodeBody(r, &payload)
+	added, err := storeOf(r).BFAdd(key, payload.Elements...)
+
+	withWriter(w).
+		Data(added).
+		Error(err).
+		WriteResponse()
+}
+
+// ?element=alice&element=bob, data is true for elements which were probably added
+func BFExistsHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	found, err := storeOf(r).BFExists(key, r.URL.Query()["element"]...)
+
+	withWriter(w).
+		Data(found).
+		Error(err).
+		WriteResponse()
+}
+
+// the key of the path is the destination
+func BFMergeHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload KeysPayload
+	decodeBody(r, &payload)
+	err := storeOf(r).BFMerge(key, payload.Keys...)
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
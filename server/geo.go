@@ -0,0 +1,83 @@
+package server
+
+import (
+	"github.com/baratov/golang-playground/store"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func registerGeoRoutes(r *mux.Router) {
+	r.HandleFunc("/geo/{key}/add", GeoAddHandler).Methods("POST")
+	r.HandleFunc("/geo/{key}/members/{member}", GeoPosHandler).Methods("GET")
+	r.HandleFunc("/geo/{key}/dist", GeoDistHandler).Methods("GET")
+	r.HandleFunc("/geo/{key}/search", GeoSearchHandler).Methods("GET")
+}
+
+type LocationsPayload struct {
+	Locations []store.GeoLocation `json:"locations"`
+}
+
+// data is number of members which were not in the index before
+func GeoAddHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload LocationsPayload
+	decodeBody(r, &payload)
+	n, err := storeOf(r).GeoAdd(key, payload.Locations...)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func GeoPosHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	pos, err := storeOf(r).GeoPos(key, mux.Vars(r)["member"])
+
+	withWriter(w).
+		Data(pos).
+		Error(err).
+		WriteResponse()
+}
+
+// ?from=member&to=other, data is in meters
+func GeoDistHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	query := r.URL.Query()
+	dist, err := storeOf(r).GeoDist(key, query.Get("from"), query.Get("to"))
+
+	withWriter(w).
+		Data(dist).
+		Error(err).
+		WriteResponse()
+}
+
+// center is ?member= or ?longitude=&latitude=, area is ?radius= or ?width=&height= in meters,
+// ?count= limits the nearest members
+func GeoSearchHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	q := store.GeoQuery{Member: r.URL.Query().Get("member")}
+	var err error
+	for name, f := range map[string]*float64{
+		"longitude": &q.Longitude,
+		"latitude":  &q.Latitude,
+		"radius":    &q.Radius,
+		"width":     &q.Width,
+		"height":    &q.Height,
+	} {
+		if *f, err = parseFloatQuery(r, name, 0); err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+	}
+	if q.Count, err = parseIntQuery(r, "count", 0); err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	found, err := storeOf(r).GeoSearch(key, q)
+
+	withWriter(w).
+		Data(found).
+		Error(err).
+		WriteResponse()
+}
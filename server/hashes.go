@@ -0,0 +1,130 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+func registerHashRoutes(r *mux.Router) {
+	r.HandleFunc("/hashes/{key}", HGetAllHandler).Methods("GET")
+	r.HandleFunc("/hashes/{key}/{field}", HGetHandler).Methods("GET")
+	r.HandleFunc("/hashes/{key}/{field}", HSetHandler).Methods("PUT")
+	r.HandleFunc("/hashes/{key}/{field}", HDelHandler).Methods("DELETE")
+	r.HandleFunc("/hashes/{key}/{field}/incr", HIncrByHandler).Methods("POST")
+	r.HandleFunc("/hashes/{key}/{field}/ttl", HTTLHandler).Methods("GET")
+	r.HandleFunc("/hashes/{key}/{field}/ttl", HExpireHandler).Methods("PUT")
+	r.HandleFunc("/hashes/{key}/{field}/ttl", HPersistHandler).Methods("DELETE")
+}
+
+type FieldPayload struct {
+	Value interface{}   `json:"value"`
+	Ttl   time.Duration `json:"ttl"` // of the field, 0 keeps it until the hash is deleted
+}
+
+// all fields, ?view=keys for sorted names of fields and ?view=len for their number
+func HGetAllHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var (
+		data interface{}
+		err  error
+	)
+	switch r.URL.Query().Get("view") {
+	case "keys":
+		data, err = storeOf(r).HKeys(key)
+	case "len":
+		data, err = storeOf(r).HLen(key)
+	default:
+		data, err = storeOf(r).HGetAll(key)
+	}
+
+	withWriter(w).
+		Data(data).
+		Error(err).
+		WriteResponse()
+}
+
+func HGetHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	val, err := storeOf(r).HGet(key, mux.Vars(r)["field"])
+
+	withWriter(w).
+		Data(val).
+		Error(err).
+		WriteResponse()
+}
+
+// data is true if the field is new
+func HSetHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload FieldPayload
+	decodeBody(r, &payload)
+	created, err := storeOf(r).HSetEx(key, mux.Vars(r)["field"], payload.Value, payload.Ttl)
+
+	withWriter(w).
+		Data(created).
+		Error(err).
+		WriteResponse()
+}
+
+// data is number of removed fields
+func HDelHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	n, err := storeOf(r).HDel(key, mux.Vars(r)["field"])
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func HIncrByHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload IncrPayload
+	decodeBody(r, &payload)
+	n, err := storeOf(r).HIncrBy(key, mux.Vars(r)["field"], payload.Delta)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func HTTLHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	ttl, err := storeOf(r).HTTL(key, mux.Vars(r)["field"])
+
+	withWriter(w).
+		Data(ttl).
+		Error(err).
+		WriteResponse()
+}
+
+func HExpireHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	field := mux.Vars(r)["field"]
+	var payload TtlPayload
+	decodeBody(r, &payload)
+
+	var err error
+	if payload.ExpireAt.IsZero() {
+		err = storeOf(r).HExpire(key, field, payload.Ttl)
+	} else {
+		err = storeOf(r).HExpireAt(key, field, payload.ExpireAt)
+	}
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
+
+func HPersistHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	err := storeOf(r).HPersist(key, mux.Vars(r)["field"])
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func registerHyperLogLogRoutes(r *mux.Router) {
+	r.HandleFunc("/hyperloglogs/{key}/add", PFAddHandler).Methods("POST")
+	r.HandleFunc("/hyperloglogs/{key}/count", PFCountHandler).Methods("GET")
+	r.HandleFunc("/hyperloglogs/{key}/merge", PFMergeHandler).Methods("POST")
+}
+
+type ElementsPayload struct {
+	Elements []string `json:"elements"`
+}
+
+type KeysPayload struct {
+	Keys []string `json:"keys"`
+}
+
+// data is true if the estimate was changed
+func PFAddHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload ElementsPayload
+	decodeBody(r, &payload)
+	changed, err := storeOf(r).PFAdd(key, payload.Elements...)
+
+	withWriter(w).
+		Data(changed).
+		Error(err).
+		WriteResponse()
+}
+
+// ?with=other&with=another counts the union
+func PFCountHandler(w http.ResponseWriter, r *http.Request) {
+	keys := append([]string{parseKey(r)}, r.URL.Query()["with"]...)
+	n, err := storeOf(r).PFCount(keys...)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+// the key of the path is the destination
+func PFMergeHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload KeysPayload
+	decodeBody(r, &payload)
+	err := storeOf(r).PFMerge(key, payload.Keys...)
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func registerListRoutes(r *mux.Router) {
+	r.HandleFunc("/lists/{key}", LRangeHandler).Methods("GET")
+	r.HandleFunc("/lists/{key}/len", LLenHandler).Methods("GET")
+	r.HandleFunc("/lists/{key}/lpush", LPushHandler).Methods("POST")
+	r.HandleFunc("/lists/{key}/rpush", RPushHandler).Methods("POST")
+	r.HandleFunc("/lists/{key}/lpop", LPopHandler).Methods("POST")
+	r.HandleFunc("/lists/{key}/rpop", RPopHandler).Methods("POST")
+	r.HandleFunc("/lists/{key}/trim", LTrimHandler).Methods("POST")
+}
+
+type ValuesPayload struct {
+	Values []interface{} `json:"values"`
+}
+
+type RangePayload struct {
+	Start int `json:"start"`
+	Stop  int `json:"stop"`
+}
+
+// ?start=0&stop=-1 by default
+func LRangeHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	start, err := parseIntQuery(r, "start", 0)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	stop, err := parseIntQuery(r, "stop", -1)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	values, err := storeOf(r).LRange(key, start, stop)
+
+	withWriter(w).
+		Data(values).
+		Error(err).
+		WriteResponse()
+}
+
+func LLenHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	n, err := storeOf(r).LLen(key)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func LPushHandler(w http.ResponseWriter, r *http.Request) {
+	pushHandler(w, r, storeOf(r).LPush)
+}
+
+func RPushHandler(w http.ResponseWriter, r *http.Request) {
+	pushHandler(w, r, storeOf(r).RPush)
+}
+
+func pushHandler(w http.ResponseWriter, r *http.Request, push func(string, ...interface{}) (int, error)) {
+	key := parseKey(r)
+	var payload ValuesPayload
+	decodeBody(r, &payload)
+	n, err := push(key, payload.Values...)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func LPopHandler(w http.ResponseWriter, r *http.Request) {
+	popHandler(w, r, storeOf(r).LPop)
+}
+
+func RPopHandler(w http.ResponseWriter, r *http.Request) {
+	popHandler(w, r, storeOf(r).RPop)
+}
+
+func popHandler(w http.ResponseWriter, r *http.Request, pop func(string) (interface{}, error)) {
+	key := parseKey(r)
+	val, err := pop(key)
+
+	withWriter(w).
+		Data(val).
+		Error(err).
+		WriteResponse()
+}
+
+func LTrimHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload RangePayload
+	decodeBody(r, &payload)
+	err := storeOf(r).LTrim(key, payload.Start, payload.Stop)
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"github.com/gorilla/mux"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// namespaces are separate stores with their own keyspace, default ttl, limits and files,
+// they are listed in a file to come back after restart, the default namespace is s itself
+
+const (
+	namespacesFile   = "./namespaces.json"
+	defNamespace     = "default"
+	storeKey         = contextKey("store")
+	errNamespaceFmt  = "namespace '%v' not found"
+	errNsExistsFmt   = "namespace '%v' already exists"
+	errNsNameFmt     = "malformed namespace name '%v'"
+	errDropDefaultNs = "default namespace can't be dropped"
+)
+
+var namespaceName = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+type contextKey string
+
+type Namespace struct {
+	Name     string        `json:"name"`
+	Ttl      time.Duration `json:"ttl"`       // of keys set without ttl, 0 keeps them until deleted
+	MaxItems int           `json:"max_items"` // 0 for no limit
+	MaxBytes int64         `json:"max_bytes"` // 0 for no limit
+}
+
+type namespace struct {
+	Namespace
+	mu      sync.RWMutex // read locked by requests, so drop waits for them
+	store   *store.Store
+	dropped bool
+	done    chan struct{} // closed before drop, so watch streams don't hold it
+}
+
+var namespaces = struct {
+	sync.RWMutex
+	m map[string]*namespace
+}{m: make(map[string]*namespace)}
+
+func registerAdminRoutes(r *mux.Router) {
+	r.HandleFunc("/api/v1/admin/namespaces", ListNamespacesHandler).Methods("GET")
+	r.HandleFunc("/api/v1/admin/namespaces/{ns}", CreateNamespaceHandler).Methods("POST")
+	r.HandleFunc("/api/v1/admin/namespaces/{ns}", DropNamespaceHandler).Methods("DELETE")
+}
+
+// store of the namespace of the request, the default one out of namespaces
+func storeOf(r *http.Request) *store.Store {
+	if st, ok := r.Context().Value(storeKey).(*store.Store); ok {
+		return st
+	}
+	return s
+}
+
+func namespaceMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["ns"]
+		if name == defNamespace {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		namespaces.RLock()
+		ns := namespaces.m[name]
+		namespaces.RUnlock()
+		if ns == nil {
+			writeNotFound(w, fmt.Errorf(errNamespaceFmt, name))
+			return
+		}
+
+		ns.mu.RLock()
+		defer ns.mu.RUnlock()
+		if ns.dropped {
+			writeNotFound(w, fmt.Errorf(errNamespaceFmt, name))
+			return
+		}
+		ctx := context.WithValue(r.Context(), storeKey, ns.store)
+		h.ServeHTTP(w, r.WithContext(context.WithValue(ctx, doneKey, ns.done)))
+	})
+}
+
+func ListNamespacesHandler(w http.ResponseWriter, _ *http.Request) {
+	namespaces.RLock()
+	list := namespacesList()
+	namespaces.RUnlock()
+
+	withWriter(w).
+		Data(list).
+		WriteResponse()
+}
+
+// payload is Namespace, its name comes from the path
+func CreateNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+	var payload Namespace
+	decodeBody(r, &payload)
+	payload.Name = mux.Vars(r)["ns"]
+	if !namespaceName.MatchString(payload.Name) {
+		writeBadRequest(w, fmt.Errorf(errNsNameFmt, payload.Name))
+		return
+	}
+
+	namespaces.Lock()
+	var err error
+	if _, ok := namespaces.m[payload.Name]; ok || payload.Name == defNamespace {
+		err = fmt.Errorf(errNsExistsFmt, payload.Name)
+	} else {
+		store.RemoveFiles(namespaceFilename(payload.Name)) // left by a namespace which was not restored
+		namespaces.m[payload.Name] = newNamespace(payload, false)
+		saveNamespaces()
+	}
+	namespaces.Unlock()
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
+
+// removes the namespace with all its keys and files once its requests are over
+func DropNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["ns"]
+	if name == defNamespace {
+		writeBadRequest(w, fmt.Errorf(errDropDefaultNs))
+		return
+	}
+
+	namespaces.Lock()
+	ns := namespaces.m[name]
+	if ns != nil {
+		delete(namespaces.m, name)
+		saveNamespaces()
+	}
+	namespaces.Unlock()
+	if ns == nil {
+		writeNotFound(w, fmt.Errorf(errNamespaceFmt, name))
+		return
+	}
+
+	close(ns.done)
+	ns.mu.Lock()
+	ns.dropped = true
+	ns.store.Drop()
+	ns.mu.Unlock()
+
+	withWriter(w).
+		Data(nil).
+		WriteResponse()
+}
+
+func newNamespace(config Namespace, restore bool) *namespace {
+	filename := namespaceFilename(config.Name)
+	ns := &namespace{Namespace: config, done: make(chan struct{})}
+	if restore {
+		ns.store = store.New(
+			store.WithCustomFilename(filename),
+			store.WithRestoreFromFile(filename),
+			store.WithWatchHistory(watchHistory),
+			store.WithDefaultTTL(config.Ttl),
+			store.WithMaxItems(config.MaxItems),
+			store.WithMaxBytes(config.MaxBytes),
+		)
+	} else {
+		ns.store = store.New(
+			store.WithCustomFilename(filename),
+			store.WithWatchHistory(watchHistory),
+			store.WithDefaultTTL(config.Ttl),
+			store.WithMaxItems(config.MaxItems),
+			store.WithMaxBytes(config.MaxBytes),
+		)
+	}
+	return ns
+}
+
+func namespaceFilename(name string) string {
+	return "./ns_" + name + ".gob"
+}
+
+// should be called under lock of namespaces
+func namespacesList() []Namespace {
+	list := make([]Namespace, 0, len(namespaces.m))
+	for _, ns := range namespaces.m {
+		list = append(list, ns.Namespace)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// should be called under write lock of namespaces
+func saveNamespaces() {
+	b, err := json.Marshal(namespacesList())
+	if err != nil {
+		panic(err)
+	}
+	if err = ioutil.WriteFile(namespacesFile+".tmp", b, 0644); err != nil {
+		panic(err)
+	}
+	if err = os.Rename(namespacesFile+".tmp", namespacesFile); err != nil {
+		panic(err)
+	}
+}
+
+// namespaces start empty unless the server restores its data
+func restoreNamespaces(restore bool) {
+	if !restore {
+		return
+	}
+	b, err := ioutil.ReadFile(namespacesFile)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	var list []Namespace
+	if err = json.Unmarshal(b, &list); err != nil {
+		panic(err)
+	}
+
+	namespaces.Lock()
+	for _, config := range list {
+		namespaces.m[config.Name] = newNamespace(config, true)
+	}
+	namespaces.Unlock()
+}
+
+func stopNamespaces() {
+	namespaces.Lock()
+	for _, ns := range namespaces.m {
+		ns.store.Stop()
+	}
+	namespaces.Unlock()
+}
+
+func writeNotFound(w http.ResponseWriter, err error) {
+	withWriter(w).
+		Status(http.StatusNotFound).
+		Error(err).
+		WriteResponse()
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"time"
+)
+
+// subscribers are connected over a websocket: they send ops to subscribe and unsubscribe, every op
+// gets a reply of its type with the number of subscriptions left or an error, messages of their
+// channels come in between, the reason is in the close message once the server closes the socket
+
+const (
+	frameMessage = "message"
+	frameError   = "error"
+	pongWait     = heartbeatInterval * 3 // of silence before the subscriber is considered gone
+	writeWait    = time.Second * 10
+)
+
+var upgrader = websocket.Upgrader{}
+
+func registerPubSubRoutes(r *mux.Router) {
+	r.HandleFunc("/channels/{channel}/publish", PublishHandler).Methods("POST")
+	r.HandleFunc("/pubsub", PubSubHandler).Methods("GET")
+}
+
+type PublishPayload struct {
+	Payload interface{} `json:"payload"`
+}
+
+// sent by subscribers, channels are patterns for psubscribe and punsubscribe,
+// no channels unsubscribes from all of them
+type PubSubOp struct {
+	Op       string   `json:"op"` // subscribe, psubscribe, unsubscribe or punsubscribe
+	Channels []string `json:"channels"`
+}
+
+// sent to subscribers, a message or a reply to an op
+type PubSubFrame struct {
+	Type    string      `json:"type"` // message, error or the op of the reply
+	Channel string      `json:"channel,omitempty"`
+	Pattern string      `json:"pattern,omitempty"` // which matched the channel
+	Payload interface{} `json:"payload,omitempty"`
+	Count   int         `json:"count,omitempty"` // of subscriptions left
+	Error   string      `json:"error,omitempty"`
+}
+
+// data is number of subscriptions which got the message
+func PublishHandler(w http.ResponseWriter, r *http.Request) {
+	var payload PublishPayload
+	decodeBody(r, &payload)
+	n := storeOf(r).Publish(mux.Vars(r)["channel"], payload.Payload)
+
+	withWriter(w).
+		Data(n).
+		WriteResponse()
+}
+
+// the connection is closed once the subscriber, the namespace or the server is gone,
+// or the subscriber doesn't keep up with its messages
+func PubSubHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // upgrader has replied with the error
+	}
+	defer conn.Close()
+	sub := storeOf(r).NewSubscription(0)
+	defer sub.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	ops := readOps(conn, done)
+
+	ping := time.NewTicker(heartbeatInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case m, ok := <-sub.C:
+			if !ok {
+				if err = sub.Err(); err != nil {
+					closeConn(conn, websocket.CloseTryAgainLater, err.Error())
+				}
+				return
+			}
+			err = writeFrame(conn, PubSubFrame{Type: frameMessage, Channel: m.Channel, Pattern: m.Pattern, Payload: m.Payload})
+		case b, ok := <-ops:
+			if !ok {
+				return
+			}
+			err = writeFrame(conn, applyOp(sub, b))
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+		case <-doneOf(r):
+			closeConn(conn, websocket.CloseGoingAway, "namespace is dropped")
+			return
+		case <-shutdown:
+			closeConn(conn, websocket.CloseGoingAway, "server is shutting down")
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// ops are read apart from writes, the channel is closed once the connection is gone
+func readOps(conn *websocket.Conn, done <-chan struct{}) <-chan []byte {
+	ops := make(chan []byte)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	go func() {
+		defer close(ops)
+		for {
+			_, b, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(pongWait))
+			select {
+			case ops <- b:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return ops
+}
+
+func applyOp(sub *store.Subscription, b []byte) PubSubFrame {
+	var op PubSubOp
+	if err := json.Unmarshal(b, &op); err != nil {
+		return PubSubFrame{Type: frameError, Error: err.Error()}
+	}
+	reply := PubSubFrame{Type: op.Op}
+	switch op.Op {
+	case "subscribe":
+		reply.Count = sub.Subscribe(op.Channels...)
+	case "psubscribe":
+		reply.Count = sub.PSubscribe(op.Channels...)
+	case "unsubscribe":
+		reply.Count = sub.Unsubscribe(op.Channels...)
+	case "punsubscribe":
+		reply.Count = sub.PUnsubscribe(op.Channels...)
+	default:
+		return PubSubFrame{Type: frameError, Error: fmt.Sprintf("unknown operation '%v'", op.Op)}
+	}
+	return reply
+}
+
+func writeFrame(conn *websocket.Conn, frame PubSubFrame) error {
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteJSON(frame)
+}
+
+func closeConn(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+}
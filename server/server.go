@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/baratov/golang-playground/store"
 	"github.com/gorilla/mux"
 	"io/ioutil"
@@ -11,6 +13,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,20 +24,27 @@ func Serve(port string, restore bool) {
 	if restore {
 		s = store.New(
 			store.WithRestoreFromFile("./store.gob"),
+			store.WithWatchHistory(watchHistory),
 		)
 	} else {
-		s = store.New()
+		s = store.New(
+			store.WithWatchHistory(watchHistory),
+		)
 	}
 
+	restoreNamespaces(restore)
+
 	r := mux.NewRouter()
 	r.Use(recoverMiddleware)
 	r.Use(basicAuthMiddleware)
 	r.HandleFunc("/health", HealthCheckHandler).Methods("GET") // healthcheck with basic auth is not ok
-	r.HandleFunc("/api/v1/keys", GetKeysHandler).Methods("GET")
-	r.HandleFunc("/api/v1/keys/{key}", GetHandler).Methods("GET")
-	r.HandleFunc("/api/v1/keys/{key}", SetHandler).Methods("POST")
-	r.HandleFunc("/api/v1/keys/{key}", UpdateHandler).Methods("PUT")
-	r.HandleFunc("/api/v1/keys/{key}", DeleteHandler).Methods("DELETE")
+	registerAdminRoutes(r)
+
+	// the same api for every namespace, the default one goes without the prefix
+	ns := r.PathPrefix("/api/v1/ns/{ns}").Subrouter()
+	ns.Use(namespaceMiddleware)
+	registerRoutes(ns)
+	registerRoutes(r.PathPrefix("/api/v1").Subrouter())
 
 	srv := &http.Server{
 		Addr:         "0.0.0.0:" + port,
@@ -43,6 +53,9 @@ func Serve(port string, restore bool) {
 		IdleTimeout:  time.Second * 15,
 		Handler:      r,
 	}
+	srv.RegisterOnShutdown(func() {
+		close(shutdown)
+	})
 
 	go func() {
 		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
@@ -60,38 +73,118 @@ func Serve(port string, restore bool) {
 
 	srv.Shutdown(ctx)
 	s.Stop()
+	stopNamespaces()
+}
+
+func registerRoutes(r *mux.Router) {
+	r.HandleFunc("/stats", StatsHandler).Methods("GET")
+	r.HandleFunc("/keys", GetKeysHandler).Methods("GET")
+	r.HandleFunc("/scan", ScanHandler).Methods("GET")
+	r.HandleFunc("/keys/{key}", GetHandler).Methods("GET")
+	r.HandleFunc("/keys/{key}", SetHandler).Methods("POST")
+	r.HandleFunc("/keys/{key}", UpdateHandler).Methods("PUT")
+	r.HandleFunc("/keys/{key}", DeleteHandler).Methods("DELETE")
+	r.HandleFunc("/txn", TxnHandler).Methods("POST")
+	r.HandleFunc("/watch", WatchHandler).Methods("GET")
+	r.HandleFunc("/keys/{key}/incr", IncrByHandler).Methods("POST")
+	r.HandleFunc("/keys/{key}/incrbyfloat", IncrByFloatHandler).Methods("POST")
+	r.HandleFunc("/keys/{key}/ttl", GetTTLHandler).Methods("GET")
+	r.HandleFunc("/keys/{key}/ttl", ExpireHandler).Methods("PUT")
+	r.HandleFunc("/keys/{key}/ttl", PersistHandler).Methods("DELETE")
+	registerListRoutes(r)
+	registerSetRoutes(r)
+	registerZSetRoutes(r)
+	registerHashRoutes(r)
+	registerStreamRoutes(r)
+	registerBitmapRoutes(r)
+	registerHyperLogLogRoutes(r)
+	registerGeoRoutes(r)
+	registerBloomRoutes(r)
+	registerSketchRoutes(r)
+	registerPubSubRoutes(r)
+}
+
+// ?match=user:* filters keys by glob pattern
+func GetKeysHandler(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	if match := r.URL.Query().Get("match"); match != "" {
+		keys = storeOf(r).KeysMatching(match)
+	} else {
+		keys = storeOf(r).Keys()
+	}
+
+	withWriter(w).
+		Data(keys).
+		WriteResponse()
+}
+
+type ScanPage struct {
+	Keys   []string `json:"keys"`
+	Cursor string   `json:"cursor"` // of the next page, "" once the scan is over
 }
 
-func GetKeysHandler(w http.ResponseWriter, _ *http.Request) {
+// ?cursor=&match=user:*&count=100, the first page has no cursor
+func ScanHandler(w http.ResponseWriter, r *http.Request) {
+	count, err := parseIntQuery(r, "count", store.DefScanCount)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	query := r.URL.Query()
+	keys, cursor := storeOf(r).Scan(query.Get("cursor"), query.Get("match"), count)
+
 	withWriter(w).
-		Data(s.Keys()).
+		Data(ScanPage{Keys: keys, Cursor: cursor}).
 		WriteResponse()
 }
 
 func GetHandler(w http.ResponseWriter, r *http.Request) {
 	key := parseKey(r)
-	val, err := s.Get(key)
+	val, version, err := storeOf(r).GetWithVersion(key)
+	if err == nil && r.Header.Get("If-None-Match") == etag(version) {
+		w.Header().Set("ETag", etag(version))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
-	withWriter(w).
+	resp := withWriter(w).
 		Data(val).
-		Error(err).
-		WriteResponse()
+		Error(err)
+	if err == nil {
+		resp.Header("ETag", etag(version))
+	}
+	resp.WriteResponse()
 }
 
+// If-None-Match: * creates the key only if it doesn't exist
 func SetHandler(w http.ResponseWriter, r *http.Request) {
 	key := parseKey(r)
 	payload := parseBody(r)
-	s.Set(key, payload.Value, payload.Ttl)
+	if r.Header.Get("If-None-Match") == "*" {
+		compareAndSwap(w, r, key, 0, payload)
+		return
+	}
+	storeOf(r).Set(key, payload.Value, payload.Ttl)
 
 	withWriter(w).
 		Data(nil).
 		WriteResponse()
 }
 
+// If-Match: "<version>" updates the key only if nobody has changed it since it was read
 func UpdateHandler(w http.ResponseWriter, r *http.Request) {
 	key := parseKey(r)
 	payload := parseBody(r)
-	err := s.Update(key, payload.Value, payload.Ttl)
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" {
+		version, err := parseETag(match)
+		if err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+		compareAndSwap(w, r, key, version, payload)
+		return
+	}
+	err := storeOf(r).Update(key, payload.Value, payload.Ttl)
 
 	withWriter(w).
 		Data(nil).
@@ -99,12 +192,136 @@ func UpdateHandler(w http.ResponseWriter, r *http.Request) {
 		WriteResponse()
 }
 
+func compareAndSwap(w http.ResponseWriter, r *http.Request, key string, version uint64, payload Payload) {
+	version, err := storeOf(r).CompareAndSwap(key, version, payload.Value, payload.Ttl)
+
+	resp := withWriter(w).
+		Data(nil).
+		Error(err)
+	if errors.Is(err, store.ErrVersionMismatch) {
+		resp.Status(http.StatusPreconditionFailed)
+	}
+	if err == nil {
+		resp.Header("ETag", etag(version))
+	}
+	resp.WriteResponse()
+}
+
 func DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	key := parseKey(r)
-	s.Delete(key)
+	storeOf(r).Delete(key)
+
+	withWriter(w).
+		Data(nil).
+		WriteResponse()
+}
+
+func IncrByHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload IncrPayload
+	decodeBody(r, &payload)
+	n, err := storeOf(r).IncrBy(key, payload.Delta)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func IncrByFloatHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload IncrFloatPayload
+	decodeBody(r, &payload)
+	f, err := storeOf(r).IncrByFloat(key, payload.Delta)
+
+	withWriter(w).
+		Data(f).
+		Error(err).
+		WriteResponse()
+}
+
+func GetTTLHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	ttl, err := storeOf(r).TTL(key)
+
+	withWriter(w).
+		Data(ttl).
+		Error(err).
+		WriteResponse()
+}
+
+func ExpireHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload TtlPayload
+	decodeBody(r, &payload)
+
+	var err error
+	if payload.ExpireAt.IsZero() {
+		err = storeOf(r).Expire(key, payload.Ttl)
+	} else {
+		err = storeOf(r).ExpireAt(key, payload.ExpireAt)
+	}
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
+
+func PersistHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	err := storeOf(r).Persist(key)
 
 	withWriter(w).
 		Data(nil).
+		Error(err).
+		WriteResponse()
+}
+
+// executes all operations under one lock, nothing is applied if any of them fails
+func TxnHandler(w http.ResponseWriter, r *http.Request) {
+	var payload TxnPayload
+	decodeBody(r, &payload)
+
+	results := make([]interface{}, len(payload.Ops))
+	err := storeOf(r).Txn(func(tx *store.Tx) error {
+		for n, op := range payload.Ops {
+			switch op.Op {
+			case "get":
+				results[n], _ = tx.Get(op.Key) // missing key is not a reason to abort
+			case "set":
+				tx.Set(op.Key, op.Value, op.Ttl)
+			case "update":
+				if err := tx.Update(op.Key, op.Value, op.Ttl); err != nil {
+					return err
+				}
+			case "delete":
+				tx.Delete(op.Key)
+			case "check":
+				if err := tx.Check(op.Key, op.Version); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown operation '%v'", op.Op)
+			}
+		}
+		return nil
+	})
+
+	resp := withWriter(w)
+	if err == nil {
+		resp.Data(results)
+	}
+	if errors.Is(err, store.ErrVersionMismatch) {
+		resp.Status(http.StatusPreconditionFailed)
+	}
+	resp.Error(err).
+		WriteResponse()
+}
+
+func StatsHandler(w http.ResponseWriter, r *http.Request) {
+	withWriter(w).
+		Data(storeOf(r).Stats()).
 		WriteResponse()
 }
 
@@ -159,7 +376,75 @@ func recoverMiddleware(h http.Handler) http.Handler {
 
 type Payload struct {
 	Value interface{}   `json:"value"`
-	Ttl   time.Duration `json:"ttl"`
+	Ttl   time.Duration `json:"ttl"` // 0 stores the key until deleted
+}
+
+type TxnPayload struct {
+	Ops []TxnOp `json:"ops"`
+}
+
+type TxnOp struct {
+	Op      string        `json:"op"` // get, set, update, delete or check
+	Key     string        `json:"key"`
+	Value   interface{}   `json:"value"`
+	Ttl     time.Duration `json:"ttl"`
+	Version uint64        `json:"version"` // for check, 0 stands for a key which doesn't exist
+}
+
+type IncrPayload struct {
+	Delta int64 `json:"delta"`
+}
+
+type IncrFloatPayload struct {
+	Delta float64 `json:"delta"`
+}
+
+type TtlPayload struct {
+	Ttl      time.Duration `json:"ttl"`
+	ExpireAt time.Time     `json:"expire_at"` // takes precedence over ttl
+}
+
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+func parseETag(tag string) (uint64, error) {
+	version, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(tag, "W/"), `"`), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed etag %v", tag)
+	}
+	return version, nil
+}
+
+func parseIntQuery(r *http.Request, name string, def int) (int, error) {
+	val := r.URL.Query().Get(name)
+	if val == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("malformed %v %v", name, val)
+	}
+	return n, nil
+}
+
+func parseFloatQuery(r *http.Request, name string, def float64) (float64, error) {
+	val := r.URL.Query().Get(name)
+	if val == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed %v %v", name, val)
+	}
+	return f, nil
+}
+
+func writeBadRequest(w http.ResponseWriter, err error) {
+	withWriter(w).
+		Status(http.StatusBadRequest).
+		Error(err).
+		WriteResponse()
 }
 
 func parseKey(r *http.Request) string {
@@ -167,15 +452,19 @@ func parseKey(r *http.Request) string {
 }
 
 func parseBody(r *http.Request) Payload {
+	var p Payload
+	decodeBody(r, &p)
+	return p
+}
+
+func decodeBody(r *http.Request, v interface{}) {
 	b, err := ioutil.ReadAll(r.Body)
 	defer r.Body.Close()
 	if err != nil {
 		panic(err) // should probably return error to handle as BadRequest
 	}
-	var p Payload
-	err = json.Unmarshal(b, &p)
+	err = json.Unmarshal(b, v)
 	if err != nil {
 		panic(err)
 	}
-	return p
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/baratov/golang-playground/server"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -26,3 +27,19 @@ func TestHealthCheckHandler(t *testing.T) {
 		t.Errorf("Expected body is %v, but found %v", expected, recorder.Body.String())
 	}
 }
+
+func TestXAddHandler_NegativeMaxLen(t *testing.T) {
+	for _, handler := range []http.HandlerFunc{server.XAddHandler, server.XTrimHandler} {
+		req, err := http.NewRequest("POST", "/streams/events/add", strings.NewReader(`{"maxlen":-1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if status := recorder.Code; status != http.StatusBadRequest {
+			t.Errorf("Expected status code is %v, but found %v", http.StatusBadRequest, status)
+		}
+	}
+}
@@ -20,12 +20,14 @@ const (
 
 type Response struct {
 	fields map[string]interface{}
+	status int
 	rw     http.ResponseWriter
 }
 
 func withWriter(w http.ResponseWriter) *Response {
 	return &Response{
 		fields: make(map[string]interface{}),
+		status: http.StatusOK,
 		rw:     w,
 	}
 }
@@ -46,6 +48,16 @@ func (r *Response) Error(err error) *Response {
 	}
 }
 
+func (r *Response) Status(code int) *Response {
+	r.status = code
+	return r
+}
+
+func (r *Response) Header(name, value string) *Response {
+	r.rw.Header().Set(name, value)
+	return r
+}
+
 func (r *Response) Field(name string, value interface{}) *Response {
 	r.fields[name] = value
 	return r
@@ -58,6 +70,6 @@ func (r *Response) WriteResponse() {
 	}
 
 	r.rw.Header().Set("Content-Type", "application/json")
-	r.rw.WriteHeader(http.StatusOK)
+	r.rw.WriteHeader(r.status)
 	r.rw.Write(j)
 }
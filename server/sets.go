@@ -0,0 +1,94 @@
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func registerSetRoutes(r *mux.Router) {
+	r.HandleFunc("/sets/{key}", SMembersHandler).Methods("GET")
+	r.HandleFunc("/sets/{key}/card", SCardHandler).Methods("GET")
+	r.HandleFunc("/sets/{key}/members/{member}", SIsMemberHandler).Methods("GET")
+	r.HandleFunc("/sets/{key}/add", SAddHandler).Methods("POST")
+	r.HandleFunc("/sets/{key}/rem", SRemHandler).Methods("POST")
+	r.HandleFunc("/sets/{key}/union", SUnionHandler).Methods("GET")
+	r.HandleFunc("/sets/{key}/inter", SInterHandler).Methods("GET")
+	r.HandleFunc("/sets/{key}/diff", SDiffHandler).Methods("GET")
+}
+
+type MembersPayload struct {
+	Members []string `json:"members"`
+}
+
+func SMembersHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	members, err := storeOf(r).SMembers(key)
+
+	withWriter(w).
+		Data(members).
+		Error(err).
+		WriteResponse()
+}
+
+func SCardHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	n, err := storeOf(r).SCard(key)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func SIsMemberHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	found, err := storeOf(r).SIsMember(key, mux.Vars(r)["member"])
+
+	withWriter(w).
+		Data(found).
+		Error(err).
+		WriteResponse()
+}
+
+func SAddHandler(w http.ResponseWriter, r *http.Request) {
+	membersHandler(w, r, storeOf(r).SAdd)
+}
+
+func SRemHandler(w http.ResponseWriter, r *http.Request) {
+	membersHandler(w, r, storeOf(r).SRem)
+}
+
+func membersHandler(w http.ResponseWriter, r *http.Request, fn func(string, ...string) (int, error)) {
+	key := parseKey(r)
+	var payload MembersPayload
+	decodeBody(r, &payload)
+	n, err := fn(key, payload.Members...)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+// ?with=other&with=another
+func SUnionHandler(w http.ResponseWriter, r *http.Request) {
+	setAlgebraHandler(w, r, storeOf(r).SUnion)
+}
+
+func SInterHandler(w http.ResponseWriter, r *http.Request) {
+	setAlgebraHandler(w, r, storeOf(r).SInter)
+}
+
+func SDiffHandler(w http.ResponseWriter, r *http.Request) {
+	setAlgebraHandler(w, r, storeOf(r).SDiff)
+}
+
+func setAlgebraHandler(w http.ResponseWriter, r *http.Request, fn func(...string) ([]string, error)) {
+	keys := append([]string{parseKey(r)}, r.URL.Query()["with"]...)
+	members, err := fn(keys...)
+
+	withWriter(w).
+		Data(members).
+		Error(err).
+		WriteResponse()
+}
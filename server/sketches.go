@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+func registerSketchRoutes(r *mux.Router) {
+	r.HandleFunc("/sketches/{key}/init", CMSInitHandler).Methods("POST")
+	r.HandleFunc("/sketches/{key}/add", CMSAddHandler).Methods("POST")
+	r.HandleFunc("/sketches/{key}/elements/{element}/incr", CMSIncrByHandler).Methods("POST")
+	r.HandleFunc("/sketches/{key}/query", CMSQueryHandler).Methods("GET")
+	r.HandleFunc("/sketches/{key}/merge", CMSMergeHandler).Methods("POST")
+}
+
+type SketchPayload struct {
+	ErrorRate   float64 `json:"error_rate"`
+	Probability float64 `json:"probability"`
+}
+
+func CMSInitHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload SketchPayload
+	decodeBody(r, &payload)
+	err := storeOf(r).CMSInit(key, payload.ErrorRate, payload.Probability)
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
+
+// data is estimated counts of the elements
+func CMSAddHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload ElementsPayload
+	decodeBody(r, &payload)
+	counts, err := storeOf(r).CMSAdd(key, payload.Elements...)
+
+	withWriter(w).
+		Data(counts).
+		Error(err).
+		WriteResponse()
+}
+
+// counts only grow, so delta must not be negative
+func CMSIncrByHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload IncrPayload
+	decodeBody(r, &payload)
+	if payload.Delta < 0 {
+		writeBadRequest(w, fmt.Errorf("negative delta %v", payload.Delta))
+		return
+	}
+	count, err := storeOf(r).CMSIncrBy(key, mux.Vars(r)["element"], uint64(payload.Delta))
+
+	withWriter(w).
+		Data(count).
+		Error(err).
+		WriteResponse()
+}
+
+// ?element=alice&element=bob
+func CMSQueryHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	counts, err := storeOf(r).CMSQuery(key, r.URL.Query()["element"]...)
+
+	withWriter(w).
+		Data(counts).
+		Error(err).
+		WriteResponse()
+}
+
+// the key of the path is the destination
+func CMSMergeHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload KeysPayload
+	decodeBody(r, &payload)
+	err := storeOf(r).CMSMerge(key, payload.Keys...)
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
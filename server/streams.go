@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"github.com/gorilla/mux"
+	"net/http"
+	"time"
+)
+
+const maxBlock = time.Minute
+
+func registerStreamRoutes(r *mux.Router) {
+	r.HandleFunc("/streams/{key}", XRangeHandler).Methods("GET")
+	r.HandleFunc("/streams/{key}/len", XLenHandler).Methods("GET")
+	r.HandleFunc("/streams/{key}/read", XReadHandler).Methods("GET")
+	r.HandleFunc("/streams/{key}/add", XAddHandler).Methods("POST")
+	r.HandleFunc("/streams/{key}/trim", XTrimHandler).Methods("POST")
+	r.HandleFunc("/streams/{key}/groups/{group}", XGroupCreateHandler).Methods("POST")
+	r.HandleFunc("/streams/{key}/groups/{group}/read", XReadGroupHandler).Methods("POST")
+	r.HandleFunc("/streams/{key}/groups/{group}/ack", XAckHandler).Methods("POST")
+	r.HandleFunc("/streams/{key}/groups/{group}/pending", XPendingHandler).Methods("GET")
+}
+
+type EntryPayload struct {
+	Fields map[string]interface{} `json:"fields"`
+	MaxLen int                    `json:"maxlen"` // 0 keeps all entries
+}
+
+type TrimPayload struct {
+	MaxLen int `json:"maxlen"`
+}
+
+type GroupPayload struct {
+	Start store.StreamID `json:"start"` // "$" for entries added later, "0" for all of them
+}
+
+type ReadGroupPayload struct {
+	Consumer string        `json:"consumer"`
+	Count    int           `json:"count"`
+	Block    time.Duration `json:"block"`
+}
+
+type AckPayload struct {
+	IDs []store.StreamID `json:"ids"`
+}
+
+// ?start=-&end=+&count=0 by default
+func XRangeHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	start, err := parseStreamIDQuery(r, "start", store.StreamID{})
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	end, err := parseStreamIDQuery(r, "end", store.LastStreamID)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	count, err := parseIntQuery(r, "count", 0)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	entries, err := storeOf(r).XRange(key, start, end, count)
+
+	withWriter(w).
+		Data(entries).
+		Error(err).
+		WriteResponse()
+}
+
+func XLenHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	n, err := storeOf(r).XLen(key)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+// ?after=$&count=0&block=5s, waits for new entries up to block
+func XReadHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	after, err := parseStreamIDQuery(r, "after", store.LastStreamID)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	count, err := parseIntQuery(r, "count", 0)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	block, err := parseDurationQuery(r, "block", 0)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	block = extendWriteDeadline(w, block)
+	ctx, cancel := blockingContext(r)
+	defer cancel()
+	entries, err := storeOf(r).XRead(ctx, key, after, count, block)
+
+	withWriter(w).
+		Data(entries).
+		Error(err).
+		WriteResponse()
+}
+
+// data is id of the new entry
+func XAddHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload EntryPayload
+	decodeBody(r, &payload)
+	if payload.MaxLen < 0 {
+		writeBadRequest(w, fmt.Errorf("negative maxlen %v", payload.MaxLen))
+		return
+	}
+	id, err := storeOf(r).XAdd(key, payload.Fields, payload.MaxLen)
+
+	withWriter(w).
+		Data(id).
+		Error(err).
+		WriteResponse()
+}
+
+func XTrimHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload TrimPayload
+	decodeBody(r, &payload)
+	if payload.MaxLen < 0 {
+		writeBadRequest(w, fmt.Errorf("negative maxlen %v", payload.MaxLen))
+		return
+	}
+	n, err := storeOf(r).XTrim(key, payload.MaxLen)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func XGroupCreateHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload GroupPayload
+	decodeBody(r, &payload)
+	err := storeOf(r).XGroupCreate(key, mux.Vars(r)["group"], payload.Start)
+
+	withWriter(w).
+		Data(nil).
+		Error(err).
+		WriteResponse()
+}
+
+func XReadGroupHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload ReadGroupPayload
+	decodeBody(r, &payload)
+	block := extendWriteDeadline(w, payload.Block)
+	ctx, cancel := blockingContext(r)
+	defer cancel()
+	entries, err := storeOf(r).XReadGroup(ctx, key, mux.Vars(r)["group"], payload.Consumer, payload.Count, block)
+
+	withWriter(w).
+		Data(entries).
+		Error(err).
+		WriteResponse()
+}
+
+// done once the request is gone, its namespace is dropped or the server shuts down,
+// so blocked reads don't hold the namespace for the whole block
+func blockingContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-doneOf(r):
+			cancel()
+		case <-shutdown:
+			cancel()
+		}
+	}()
+	return ctx, cancel
+}
+
+// data is number of entries which were pending
+func XAckHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload AckPayload
+	decodeBody(r, &payload)
+	n, err := storeOf(r).XAck(key, mux.Vars(r)["group"], payload.IDs...)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func XPendingHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	pending, err := storeOf(r).XPending(key, mux.Vars(r)["group"])
+
+	withWriter(w).
+		Data(pending).
+		Error(err).
+		WriteResponse()
+}
+
+func parseStreamIDQuery(r *http.Request, name string, def store.StreamID) (store.StreamID, error) {
+	val := r.URL.Query().Get(name)
+	if val == "" {
+		return def, nil
+	}
+	return store.ParseStreamID(val)
+}
+
+func parseDurationQuery(r *http.Request, name string, def time.Duration) (time.Duration, error) {
+	val := r.URL.Query().Get(name)
+	if val == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("malformed %v %v", name, val)
+	}
+	return d, nil
+}
+
+// server write timeout is too short for blocking reads, returns the block cut to maxBlock
+func extendWriteDeadline(w http.ResponseWriter, block time.Duration) time.Duration {
+	if block > maxBlock {
+		block = maxBlock
+	}
+	if block > 0 {
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(block + time.Second))
+	}
+	return block
+}
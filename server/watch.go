@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// changes of keys are streamed as server-sent events, each event has the epoch and the seq of the store
+// as id, so a client which lost the connection comes back with Last-Event-ID and gets what it missed
+// from the history of the store, if it's too late for that or the store was restarted since then
+// the stream starts with a reset event
+
+const (
+	watchHistory      = 1000 // events kept by every shard of a store to resume streams
+	heartbeatInterval = time.Second * 15
+	doneKey           = contextKey("done")
+	eventReset        = "reset" // events were lost, keys should be read again
+	eventError        = "error" // the stream is over, the client should resume
+)
+
+var (
+	shutdown       = make(chan struct{}) // closed once the server shuts down, so streams don't hold it
+	watchHeartbeat = heartbeatInterval   // shorter in tests
+)
+
+// ?prefix=user: watches keys with the prefix, Last-Event-ID header or ?last_event_id= resumes the stream
+func WatchHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id") // browsers can't set headers of EventSource
+	}
+
+	var watcher *store.Watcher
+	reset := false
+	if lastID == "" {
+		watcher = storeOf(r).Watch(prefix, 0)
+	} else {
+		epoch, seq, err := parseEventID(lastID)
+		if err != nil {
+			writeBadRequest(w, err)
+			return
+		}
+		watcher, err = storeOf(r).WatchFrom(prefix, 0, epoch, seq)
+		if errors.Is(err, store.ErrHistoryGone) {
+			watcher, reset = storeOf(r).Watch(prefix, 0), true
+		}
+	}
+	defer watcher.Close()
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{}) // the stream lasts until the client or the server is gone
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if reset {
+		writeEvent(w, "", eventReset, nil)
+	}
+	rc.Flush()
+
+	heartbeat := time.NewTicker(watchHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case e, ok := <-watcher.C:
+			if !ok {
+				if err := watcher.Err(); err != nil {
+					writeEvent(w, "", eventError, err.Error())
+					rc.Flush()
+				}
+				return
+			}
+			writeEvent(w, eventID(storeOf(r).WatchEpoch(), e.Seq), string(e.Type), e)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-r.Context().Done():
+			return
+		case <-doneOf(r):
+			return
+		case <-shutdown:
+			return
+		}
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+// epoch-seq
+func eventID(epoch, seq uint64) string {
+	return fmt.Sprintf("%v-%v", epoch, seq)
+}
+
+func parseEventID(id string) (uint64, uint64, error) {
+	parts := strings.Split(id, "-")
+	if len(parts) == 2 {
+		epoch, err1 := strconv.ParseUint(parts[0], 10, 64)
+		seq, err2 := strconv.ParseUint(parts[1], 10, 64)
+		if err1 == nil && err2 == nil {
+			return epoch, seq, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("malformed last event id %v", id)
+}
+
+func writeEvent(w http.ResponseWriter, id, event string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	if id != "" {
+		fmt.Fprintf(w, "id: %v\n", id)
+	}
+	fmt.Fprintf(w, "event: %v\ndata: %s\n\n", event, b)
+}
+
+// closed once the namespace of the request is dropped, nil for the default one
+func doneOf(r *http.Request) <-chan struct{} {
+	done, _ := r.Context().Value(doneKey).(chan struct{})
+	return done
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"github.com/baratov/golang-playground/store"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// a server of WatchHandler over the store, as namespaceMiddleware does it
+func newWatchServer(t *testing.T, st *store.Store) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WatchHandler(w, r.WithContext(context.WithValue(r.Context(), storeKey, st)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newWatchStore(t *testing.T, history int) *store.Store {
+	st := store.New(
+		store.WithCustomFilename(filepath.Join(t.TempDir(), "store.gob")),
+		store.WithWatchHistory(history),
+		store.WithShards(1),
+	)
+	t.Cleanup(st.Stop)
+	return st
+}
+
+// the watcher is there once the response comes, the stream is closed after the test or by cancel
+func openStream(t *testing.T, url, lastID string) (*bufio.Reader, int, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return bufio.NewReader(resp.Body), resp.StatusCode, cancel
+}
+
+// skips heartbeats
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Expected an event, but found %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && e.event != "":
+			return e
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestWatchHandler_Resume(t *testing.T) {
+	st := newWatchStore(t, 10)
+	srv := newWatchServer(t, st)
+
+	stream, _, cancel := openStream(t, srv.URL, "")
+	st.Set("key1", 1, time.Minute)
+	first := readEvent(t, stream)
+	if first.event != string(store.EventSet) || !strings.Contains(first.data, `"key":"key1"`) {
+		t.Errorf("Expected set of key1, but found %v", first)
+	}
+	cancel()
+
+	st.Set("key2", 2, time.Minute)
+	st.Delete("key1")
+	stream, _, _ = openStream(t, srv.URL, first.id)
+	for _, expected := range []string{`"type":"set","key":"key2"`, `"type":"delete","key":"key1"`} {
+		if e := readEvent(t, stream); !strings.Contains(e.data, expected) || e.id == "" {
+			t.Errorf("Expected event with %v, but found %v", expected, e)
+		}
+	}
+}
+
+func TestWatchHandler_Reset(t *testing.T) {
+	st := newWatchStore(t, 2)
+	srv := newWatchServer(t, st)
+	for n := 0; n < 5; n++ {
+		st.Set("key", n, time.Minute)
+	}
+
+	cases := []struct {
+		name   string
+		lastID string
+	}{
+		{"out of history", eventID(st.WatchEpoch(), 1)},
+		{"another run", eventID(st.WatchEpoch()+1, 5)},
+	}
+	for _, c := range cases {
+		stream, _, _ := openStream(t, srv.URL, c.lastID)
+		if e := readEvent(t, stream); e.event != eventReset {
+			t.Errorf("Expected reset for the event %v, but found %v", c.name, e)
+		}
+	}
+
+	if _, status, _ := openStream(t, srv.URL, "5"); status != http.StatusBadRequest {
+		t.Errorf("Expected status code is %v for a malformed id, but found %v", http.StatusBadRequest, status)
+	}
+}
+
+func TestWatchHandler_Heartbeat(t *testing.T) {
+	interval := watchHeartbeat
+	watchHeartbeat = time.Millisecond * 10
+	defer func() { watchHeartbeat = interval }()
+
+	srv := newWatchServer(t, newWatchStore(t, 0))
+	stream, _, _ := openStream(t, srv.URL, "")
+	line, err := stream.ReadString('\n')
+	if err != nil || line != ": heartbeat\n" {
+		t.Errorf("Expected heartbeat, but found %q, %v", line, err)
+	}
+}
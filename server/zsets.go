@@ -0,0 +1,112 @@
+package server
+
+import (
+	"github.com/baratov/golang-playground/store"
+	"github.com/gorilla/mux"
+	"math"
+	"net/http"
+)
+
+func registerZSetRoutes(r *mux.Router) {
+	r.HandleFunc("/zsets/{key}", ZRangeByRankHandler).Methods("GET")
+	r.HandleFunc("/zsets/{key}/byscore", ZRangeByScoreHandler).Methods("GET")
+	r.HandleFunc("/zsets/{key}/members/{member}", ZScoreHandler).Methods("GET")
+	r.HandleFunc("/zsets/{key}/members/{member}/rank", ZRankHandler).Methods("GET")
+	r.HandleFunc("/zsets/{key}/members/{member}/incr", ZIncrByHandler).Methods("POST")
+	r.HandleFunc("/zsets/{key}/add", ZAddHandler).Methods("POST")
+	r.HandleFunc("/zsets/{key}/rem", ZRemHandler).Methods("POST")
+}
+
+type ScoredMembersPayload struct {
+	Members []store.ScoredMember `json:"members"`
+}
+
+// ?start=0&stop=-1 by default
+func ZRangeByRankHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	start, err := parseIntQuery(r, "start", 0)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	stop, err := parseIntQuery(r, "stop", -1)
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	members, err := storeOf(r).ZRangeByRank(key, start, stop)
+
+	withWriter(w).
+		Data(members).
+		Error(err).
+		WriteResponse()
+}
+
+// ?min=-inf&max=inf by default
+func ZRangeByScoreHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	min, err := parseFloatQuery(r, "min", math.Inf(-1))
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	max, err := parseFloatQuery(r, "max", math.Inf(1))
+	if err != nil {
+		writeBadRequest(w, err)
+		return
+	}
+	members, err := storeOf(r).ZRangeByScore(key, min, max)
+
+	withWriter(w).
+		Data(members).
+		Error(err).
+		WriteResponse()
+}
+
+func ZScoreHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	score, err := storeOf(r).ZScore(key, mux.Vars(r)["member"])
+
+	withWriter(w).
+		Data(score).
+		Error(err).
+		WriteResponse()
+}
+
+func ZRankHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	rank, err := storeOf(r).ZRank(key, mux.Vars(r)["member"])
+
+	withWriter(w).
+		Data(rank).
+		Error(err).
+		WriteResponse()
+}
+
+func ZIncrByHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload IncrFloatPayload
+	decodeBody(r, &payload)
+	score, err := storeOf(r).ZIncrBy(key, mux.Vars(r)["member"], payload.Delta)
+
+	withWriter(w).
+		Data(score).
+		Error(err).
+		WriteResponse()
+}
+
+func ZAddHandler(w http.ResponseWriter, r *http.Request) {
+	key := parseKey(r)
+	var payload ScoredMembersPayload
+	decodeBody(r, &payload)
+	n, err := storeOf(r).ZAdd(key, payload.Members...)
+
+	withWriter(w).
+		Data(n).
+		Error(err).
+		WriteResponse()
+}
+
+func ZRemHandler(w http.ResponseWriter, r *http.Request) {
+	membersHandler(w, r, storeOf(r).ZRem)
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/bits"
+)
+
+// bitmaps are plain []byte values, bit 0 is the most significant bit of the first byte as in redis,
+// SetBit grows the value with zero bytes, byte ranges of BitCount and BitPos are inclusive
+// and can be negative as in LRange
+
+type BitOperation string
+
+const (
+	BitAnd BitOperation = "AND"
+	BitOr  BitOperation = "OR"
+	BitXor BitOperation = "XOR"
+	BitNot BitOperation = "NOT" // of a single key
+
+	errNotABitFmt      = "bit is not 0 or 1: %v"
+	errBitOffsetFmt    = "bit offset %v is out of range"
+	errBitOperationFmt = "unknown bit operation '%v'"
+	errBitNotArity     = "NOT takes a single key"
+	errBitOpNoKeys     = "operation takes at least one key"
+
+	maxBitOffset = 1<<32 - 1 // 512MB value
+)
+
+func init() {
+	gob.Register(&setBit{})
+}
+
+// returns the bit which was at the offset before
+func (s *Store) SetBit(key string, offset int, bit int) (int, error) {
+	if bit != 0 && bit != 1 {
+		return 0, fmt.Errorf(errNotABitFmt, bit)
+	}
+	if offset < 0 || offset > maxBitOffset {
+		return 0, fmt.Errorf(errBitOffsetFmt, offset)
+	}
+
+	m := &setBit{Offset: offset, Bit: bit}
+	err := s.mutate(key, func() interface{} {
+		return []byte{}
+	}, m)
+	return m.old, err
+}
+
+// the value grows by append, so it is replaced rather than changed in place
+type setBit struct {
+	Offset int
+	Bit    int
+	old    int
+	value  []byte
+}
+
+func (m *setBit) apply(key string, val interface{}) (int64, error) {
+	b, ok := val.([]byte)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	var delta int64
+	n := m.Offset / 8
+	if n >= len(b) {
+		delta = int64(n + 1 - len(b))
+		b = append(b, make([]byte, n+1-len(b))...)
+	}
+	m.value = b
+	mask := byte(0x80) >> uint(m.Offset%8)
+	if b[n]&mask != 0 {
+		m.old = 1
+	}
+	if m.old == m.Bit && delta == 0 {
+		return 0, errUnchanged
+	}
+	if m.Bit == 1 {
+		b[n] |= mask
+	} else {
+		b[n] &^= mask
+	}
+	return delta, nil
+}
+
+// the mutation may stay in the watch history, so it doesn't keep the value
+func (m *setBit) replaced() interface{} {
+	v := m.value
+	m.value = nil
+	return v
+}
+
+// bits out of the value are 0
+func (s *Store) GetBit(key string, offset int) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf(errBitOffsetFmt, offset)
+	}
+
+	bit := 0
+	err := s.read(key, func(val interface{}) error {
+		b, err := asBitmap(key, val)
+		if err == nil && offset/8 < len(b) && b[offset/8]&(byte(0x80)>>uint(offset%8)) != 0 {
+			bit = 1
+		}
+		return err
+	})
+	return bit, err
+}
+
+// number of set bits in bytes from start to end, 0 and -1 for the whole value
+func (s *Store) BitCount(key string, start, end int) (int, error) {
+	count := 0
+	err := s.read(key, func(val interface{}) error {
+		b, err := asBitmap(key, val)
+		if err != nil {
+			return err
+		}
+		start, end := bounds(len(b), start, end)
+		for _, c := range b[start:end] {
+			count += bits.OnesCount8(c)
+		}
+		return nil
+	})
+	return count, err
+}
+
+// position of the first bit set to the given one in bytes from start to end, -1 if there is none
+func (s *Store) BitPos(key string, bit int, start, end int) (int, error) {
+	if bit != 0 && bit != 1 {
+		return 0, fmt.Errorf(errNotABitFmt, bit)
+	}
+
+	pos := -1
+	err := s.read(key, func(val interface{}) error {
+		b, err := asBitmap(key, val)
+		if err != nil {
+			return err
+		}
+		start, end := bounds(len(b), start, end)
+		for n := start; n < end; n++ {
+			c := b[n]
+			if bit == 0 {
+				c = ^c
+			}
+			if c != 0 {
+				pos = n*8 + bits.LeadingZeros8(c)
+				break
+			}
+		}
+		return nil
+	})
+	return pos, err
+}
+
+// stores the result of the operation over the keys to dest, shorter values are padded with zero bytes,
+// returns length of the result in bytes, dest is deleted for an empty result
+func (s *Store) BitOp(op BitOperation, dest string, keys ...string) (int, error) {
+	switch op {
+	case BitAnd, BitOr, BitXor:
+		if len(keys) == 0 {
+			return 0, fmt.Errorf(errBitOpNoKeys)
+		}
+	case BitNot:
+		if len(keys) != 1 {
+			return 0, fmt.Errorf(errBitNotArity)
+		}
+	default:
+		return 0, fmt.Errorf(errBitOperationFmt, op)
+	}
+
+	var result []byte
+	err := s.Txn(func(tx *Tx) error {
+		values := make([][]byte, len(keys))
+		for n, key := range keys {
+			val, err := tx.Get(key)
+			if err != nil {
+				continue // missing key is an empty bitmap
+			}
+			if values[n], err = asBitmap(key, val); err != nil {
+				return err
+			}
+		}
+
+		result = bitOp(op, values)
+		if len(result) == 0 {
+			tx.Delete(dest)
+		} else {
+			tx.Set(dest, result, NoExpiration)
+		}
+		return nil
+	})
+	return len(result), err
+}
+
+func bitOp(op BitOperation, values [][]byte) []byte {
+	size := 0
+	for _, v := range values {
+		if len(v) > size {
+			size = len(v)
+		}
+	}
+
+	result := make([]byte, size)
+	if op == BitNot {
+		for n, c := range values[0] {
+			result[n] = ^c
+		}
+		return result
+	}
+
+	copy(result, values[0])
+	for _, v := range values[1:] {
+		for n := range result {
+			var c byte
+			if n < len(v) {
+				c = v[n]
+			}
+			switch op {
+			case BitAnd:
+				result[n] &= c
+			case BitOr:
+				result[n] |= c
+			case BitXor:
+				result[n] ^= c
+			}
+		}
+	}
+	return result
+}
+
+// missing key is an empty bitmap
+func asBitmap(key string, val interface{}) ([]byte, error) {
+	if val == nil {
+		return nil, nil
+	}
+	b, ok := val.([]byte)
+	if !ok {
+		return nil, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	return b, nil
+}
@@ -0,0 +1,135 @@
+package store_test
+
+import (
+	"github.com/baratov/golang-playground/store"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetBitGetBit(t *testing.T) {
+	s := newStore(t)
+
+	old, err := s.SetBit("flags", 9, 1)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if old != 0 {
+		t.Errorf("Expected old bit is 0, but found %v", old)
+	}
+	if old, _ = s.SetBit("flags", 9, 0); old != 1 {
+		t.Errorf("Expected old bit is 1, but found %v", old)
+	}
+	s.SetBit("flags", 0, 1)
+	s.SetBit("flags", 7, 1)
+
+	expected := []byte{0x81, 0x00}
+	if val, _ := s.Get("flags"); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected value is %v, but found %v", expected, val)
+	}
+	for offset, expected := range map[int]int{0: 1, 1: 0, 7: 1, 9: 0, 1000: 0} {
+		if bit, _ := s.GetBit("flags", offset); bit != expected {
+			t.Errorf("Expected bit at %v is %v, but found %v", offset, expected, bit)
+		}
+	}
+
+	if _, err = s.SetBit("flags", 1, 2); err == nil {
+		t.Errorf("Expected error for bit 2")
+	}
+	s.Set("someKey", "string", time.Minute)
+	_, err = s.SetBit("someKey", 1, 1)
+	expectedErr := "wrong type for key 'someKey'"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error is %s, but found %v", expectedErr, err)
+	}
+}
+
+func TestBitCountBitPos(t *testing.T) {
+	s := newStore(t)
+	s.Set("bits", []byte{0xff, 0xf0, 0x00, 0x01}, store.NoExpiration)
+
+	for _, c := range []struct{ start, end, expected int }{
+		{0, -1, 13},
+		{1, 1, 4},
+		{-2, -1, 1},
+		{0, -100, 0},
+	} {
+		if n, _ := s.BitCount("bits", c.start, c.end); n != c.expected {
+			t.Errorf("Expected count from %v to %v is %v, but found %v", c.start, c.end, c.expected, n)
+		}
+	}
+
+	for _, c := range []struct{ bit, start, end, expected int }{
+		{0, 0, -1, 12},
+		{1, 2, -1, 31},
+		{1, 2, 2, -1},
+		{1, 0, -100, -1},
+	} {
+		if pos, _ := s.BitPos("bits", c.bit, c.start, c.end); pos != c.expected {
+			t.Errorf("Expected position of %v from %v to %v is %v, but found %v", c.bit, c.start, c.end, c.expected, pos)
+		}
+	}
+}
+
+func TestBitOp(t *testing.T) {
+	s := newStore(t)
+	s.Set("a", []byte{0xf0, 0xff}, store.NoExpiration)
+	s.Set("b", []byte{0x3c}, store.NoExpiration)
+
+	for _, c := range []struct {
+		op       store.BitOperation
+		keys     []string
+		expected []byte
+	}{
+		{store.BitAnd, []string{"a", "b"}, []byte{0x30, 0x00}},
+		{store.BitOr, []string{"a", "b"}, []byte{0xfc, 0xff}},
+		{store.BitXor, []string{"a", "b"}, []byte{0xcc, 0xff}},
+		{store.BitNot, []string{"b"}, []byte{0xc3}},
+	} {
+		n, err := s.BitOp(c.op, "dest", c.keys...)
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if n != len(c.expected) {
+			t.Errorf("Expected length of %s is %v, but found %v", c.op, len(c.expected), n)
+		}
+		if val, _ := s.Get("dest"); !reflect.DeepEqual(val, c.expected) {
+			t.Errorf("Expected result of %s is %v, but found %v", c.op, c.expected, val)
+		}
+	}
+
+	if _, err := s.BitOp(store.BitNot, "dest", "a", "b"); err == nil {
+		t.Errorf("Expected error for NOT of two keys")
+	}
+	if _, err := s.BitOp("NAND", "dest", "a"); err == nil {
+		t.Errorf("Expected error for unknown operation")
+	}
+	for _, op := range []store.BitOperation{store.BitAnd, store.BitOr, store.BitXor} {
+		if _, err := s.BitOp(op, "dest"); err == nil {
+			t.Errorf("Expected error for %v without keys", op)
+		}
+	}
+	s.Set("other", 1, store.NoExpiration) // would hang if a shard was left locked
+}
+
+func TestBitmap_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.SetBit("flags", 3, 1)
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.SetBit("flags", 12, 1)
+	if n, _ := r.BitCount("flags", 0, -1); n != 2 {
+		t.Errorf("Expected 2 set bits, but found %v", n)
+	}
+
+	//teardown
+	r.Stop()
+}
@@ -0,0 +1,278 @@
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// BloomFilter tells if an element was probably added or definitely was not, it is scalable:
+// once the last filter is full a new one twice as big with a half of its error rate is added,
+// so the total error rate stays below the configured one however many elements are added
+
+const (
+	DefBloomErrorRate = 0.01
+	DefBloomCapacity  = 100
+	bloomGrowth       = 2   // of capacity of each next filter
+	bloomTightening   = 0.5 // of error rate of each next filter
+
+	errBloomParamsFmt  = "error rate %v must be within (0, 1) and capacity %v positive"
+	errKeyExistsFmt    = "key '%v' already exists"
+	errIncompatibleFmt = "key '%v' has different parameters"
+)
+
+func init() {
+	gob.Register(&BloomFilter{})
+	gob.Register(&bloomAdd{})
+}
+
+type BloomFilter struct {
+	ErrorRate float64
+	Capacity  int // of the first filter
+	Filters   []BloomLayer
+}
+
+type BloomLayer struct {
+	Bits     []byte
+	Hashes   int
+	Capacity int
+	Count    int
+}
+
+func newBloomFilter(errorRate float64, capacity int) *BloomFilter {
+	return &BloomFilter{
+		ErrorRate: errorRate,
+		Capacity:  capacity,
+		Filters:   []BloomLayer{newBloomLayer(errorRate*(1-bloomTightening), capacity)},
+	}
+}
+
+// optimal number of bits and hashes for the capacity and the error rate
+func newBloomLayer(errorRate float64, capacity int) BloomLayer {
+	m := math.Ceil(-float64(capacity) * math.Log(errorRate) / (math.Ln2 * math.Ln2))
+	k := math.Round(m / float64(capacity) * math.Ln2)
+	return BloomLayer{
+		Bits:     make([]byte, int(m+7)/8),
+		Hashes:   int(math.Max(k, 1)),
+		Capacity: capacity,
+	}
+}
+
+func validBloom(errorRate float64, capacity int) bool {
+	return errorRate > 0 && errorRate < 1 && capacity > 0
+}
+
+// never empty, removed only by Delete
+func (b *BloomFilter) len() int {
+	return 1
+}
+
+func (b *BloomFilter) clone() interface{} {
+	c := &BloomFilter{ErrorRate: b.ErrorRate, Capacity: b.Capacity, Filters: make([]BloomLayer, len(b.Filters))}
+	for n, l := range b.Filters {
+		l.Bits = append([]byte(nil), l.Bits...)
+		c.Filters[n] = l
+	}
+	return c
+}
+
+// positions of the element by double hashing of a single 64-bit hash
+func (l *BloomLayer) positions(x uint64, fn func(pos uint64) bool) bool {
+	m := uint64(len(l.Bits)) * 8
+	h1, h2 := x, bits.RotateLeft64(x, 32)|1
+	for n := 0; n < l.Hashes; n++ {
+		if !fn((h1 + uint64(n)*h2) % m) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *BloomLayer) contains(x uint64) bool {
+	return l.positions(x, func(pos uint64) bool {
+		return l.Bits[pos/8]&(1<<(pos%8)) != 0
+	})
+}
+
+func (l *BloomLayer) add(x uint64) {
+	l.positions(x, func(pos uint64) bool {
+		l.Bits[pos/8] |= 1 << (pos % 8)
+		return true
+	})
+	l.Count++
+}
+
+func (b *BloomFilter) contains(element string) bool {
+	x := elementHash(element)
+	for n := range b.Filters {
+		if b.Filters[n].contains(x) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns true if the element was not there and how the size of the filter has changed
+func (b *BloomFilter) add(element string) (bool, int64) {
+	if b.contains(element) {
+		return false, 0
+	}
+	var delta int64
+	last := &b.Filters[len(b.Filters)-1]
+	if last.Count >= last.Capacity {
+		b.Filters = append(b.Filters, b.nextLayer())
+		last = &b.Filters[len(b.Filters)-1]
+		delta = int64(len(last.Bits))
+	}
+	last.add(elementHash(element))
+	return true, delta
+}
+
+func (b *BloomFilter) nextLayer() BloomLayer {
+	n := len(b.Filters)
+	capacity := b.Capacity * int(math.Pow(bloomGrowth, float64(n)))
+	errorRate := b.ErrorRate * (1 - bloomTightening) * math.Pow(bloomTightening, float64(n))
+	return newBloomLayer(errorRate, capacity)
+}
+
+// ors filters of the same level, counts are summed up, so merged filters grow sooner rather
+// than exceed the error rate, returns how the size of the filter has changed
+func (b *BloomFilter) merge(other *BloomFilter) int64 {
+	var delta int64
+	for n, l := range other.Filters {
+		if n == len(b.Filters) {
+			l.Bits = append([]byte(nil), l.Bits...)
+			b.Filters = append(b.Filters, l)
+			delta += int64(len(l.Bits))
+			continue
+		}
+		dst := &b.Filters[n]
+		for i := range l.Bits {
+			dst.Bits[i] |= l.Bits[i]
+		}
+		if dst.Count += l.Count; dst.Count > dst.Capacity {
+			dst.Count = dst.Capacity
+		}
+	}
+	return delta
+}
+
+func (b *BloomFilter) compatible(other *BloomFilter) bool {
+	return b.ErrorRate == other.ErrorRate && b.Capacity == other.Capacity
+}
+
+// creates an empty filter with the given parameters, the key must not exist
+func (s *Store) BFReserve(key string, errorRate float64, capacity int) error {
+	if !validBloom(errorRate, capacity) {
+		return fmt.Errorf(errBloomParamsFmt, errorRate, capacity)
+	}
+	created := false
+	return s.change(key, func() interface{} {
+		created = true
+		return newBloomFilter(errorRate, capacity)
+	}, func(val interface{}) (int64, error) {
+		if !created {
+			return 0, fmt.Errorf(errKeyExistsFmt, key)
+		}
+		return 0, nil
+	})
+}
+
+// returns true for elements which were definitely not added before, a missing key is created
+// with DefBloomErrorRate and DefBloomCapacity
+func (s *Store) BFAdd(key string, elements ...string) ([]bool, error) {
+	m := &bloomAdd{Elements: elements}
+	err := s.mutate(key, func() interface{} {
+		return newBloomFilter(DefBloomErrorRate, DefBloomCapacity)
+	}, m)
+	return m.added, err
+}
+
+type bloomAdd struct {
+	Elements []string
+	added    []bool
+}
+
+func (m *bloomAdd) apply(key string, val interface{}) (int64, error) {
+	b, ok := val.(*BloomFilter)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	m.added = make([]bool, len(m.Elements))
+	var delta int64
+	changed := false
+	for n, e := range m.Elements {
+		var d int64
+		m.added[n], d = b.add(e)
+		delta += d
+		changed = changed || m.added[n]
+	}
+	if !changed {
+		return 0, errUnchanged
+	}
+	return delta, nil
+}
+
+// returns true for elements which were probably added, missing key has no elements
+func (s *Store) BFExists(key string, elements ...string) ([]bool, error) {
+	found := make([]bool, len(elements))
+	err := s.read(key, func(val interface{}) error {
+		if val == nil {
+			return nil
+		}
+		b, ok := val.(*BloomFilter)
+		if !ok {
+			return fmt.Errorf(errWrongTypeFmt, key)
+		}
+		for n, e := range elements {
+			found[n] = b.contains(e)
+		}
+		return nil
+	})
+	return found, err
+}
+
+// merges the keys into dest, all of them must have the same error rate and capacity,
+// a missing dest is created with parameters of the keys
+func (s *Store) BFMerge(dest string, keys ...string) error {
+	var union *BloomFilter
+	err := s.readMany(keys, func(vals []interface{}) error {
+		for n, val := range vals {
+			if val == nil {
+				continue
+			}
+			b, ok := val.(*BloomFilter)
+			if !ok {
+				return fmt.Errorf(errWrongTypeFmt, keys[n])
+			}
+			if union == nil {
+				union = newBloomFilter(b.ErrorRate, b.Capacity)
+			}
+			if !union.compatible(b) {
+				return fmt.Errorf(errIncompatibleFmt, keys[n])
+			}
+			union.merge(b)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if union == nil {
+		union = newBloomFilter(DefBloomErrorRate, DefBloomCapacity)
+	}
+
+	return s.change(dest, func() interface{} {
+		return newBloomFilter(union.ErrorRate, union.Capacity)
+	}, func(val interface{}) (int64, error) {
+		b, ok := val.(*BloomFilter)
+		if !ok {
+			return 0, fmt.Errorf(errWrongTypeFmt, dest)
+		}
+		if !b.compatible(union) {
+			return 0, fmt.Errorf(errIncompatibleFmt, dest)
+		}
+		return b.merge(union), nil
+	})
+}
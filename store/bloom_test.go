@@ -0,0 +1,147 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"testing"
+	"time"
+)
+
+func TestBFAddBFExists(t *testing.T) {
+	s := newStore(t)
+
+	added, err := s.BFAdd("seen", "alice", "bob", "alice")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if !added[0] || !added[1] || added[2] {
+		t.Errorf("Expected alice and bob to be added once, but found %v", added)
+	}
+
+	found, err := s.BFExists("seen", "alice", "carol")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if !found[0] || found[1] {
+		t.Errorf("Expected only alice to be found, but found %v", found)
+	}
+	if found, _ = s.BFExists("nonExisting", "alice"); found[0] {
+		t.Errorf("Expected nothing to be found in a missing key")
+	}
+
+	s.Set("someKey", 123, time.Minute)
+	_, err = s.BFAdd("someKey", "alice")
+	expected := "wrong type for key 'someKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestBFReserve(t *testing.T) {
+	s := newStore(t)
+
+	if err := s.BFReserve("seen", 0.001, 1000); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	err := s.BFReserve("seen", 0.001, 1000)
+	expected := "key 'seen' already exists"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+	if err = s.BFReserve("other", 1, 1000); err == nil {
+		t.Errorf("Expected error for error rate 1")
+	}
+	if _, err = s.Get("other"); err == nil {
+		t.Errorf("Expected the key not to be created")
+	}
+}
+
+// filters are added as the first one is full, while the error rate stays within the configured one
+func TestBloomFilter_Scaling(t *testing.T) {
+	s := newStore(t)
+	s.BFReserve("seen", 0.01, 1000)
+
+	batch := make([]string, 0, 1000)
+	for n := 0; n < 20000; n++ {
+		batch = append(batch, fmt.Sprintf("user%d", n))
+		if len(batch) == cap(batch) {
+			s.BFAdd("seen", batch...)
+			batch = batch[:0]
+		}
+	}
+
+	val, _ := s.Get("seen")
+	if layers := len(val.(*store.BloomFilter).Filters); layers != 5 {
+		t.Errorf("Expected 5 filters, but found %v", layers)
+	}
+
+	missing := make([]string, 20000)
+	for n := range missing {
+		missing[n] = fmt.Sprintf("other%d", n)
+	}
+	found, _ := s.BFExists("seen", missing...)
+	positives := 0
+	for _, f := range found {
+		if f {
+			positives++
+		}
+	}
+	if rate := float64(positives) / float64(len(missing)); rate > 0.01 {
+		t.Errorf("Expected error rate is below 0.01, but found %v", rate)
+	}
+
+	added := make([]string, 20000)
+	for n := range added {
+		added[n] = fmt.Sprintf("user%d", n)
+	}
+	found, _ = s.BFExists("seen", added...)
+	for n, f := range found {
+		if !f {
+			t.Errorf("Expected %v to be found", added[n])
+			break
+		}
+	}
+}
+
+func TestBFMerge(t *testing.T) {
+	s := newStore(t)
+	s.BFAdd("monday", "alice", "bob")
+	s.BFAdd("tuesday", "carol")
+
+	if err := s.BFMerge("week", "monday", "tuesday"); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	found, _ := s.BFExists("week", "alice", "bob", "carol", "dave")
+	if !found[0] || !found[1] || !found[2] || found[3] {
+		t.Errorf("Expected alice, bob and carol to be found, but found %v", found)
+	}
+
+	s.BFReserve("other", 0.001, 1000)
+	err := s.BFMerge("week", "monday", "other")
+	expected := "key 'other' has different parameters"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestBloomFilter_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.BFAdd("seen", "alice")
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.BFAdd("seen", "bob")
+	if found, _ := r.BFExists("seen", "alice", "bob"); !found[0] || !found[1] {
+		t.Errorf("Expected alice and bob to be found, but found %v", found)
+	}
+
+	//teardown
+	r.Stop()
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// optimistic concurrency: every change of a key gives it a greater version,
+// a writer passes the version it has read and fails if somebody has changed the key in between
+
+var ErrVersionMismatch = errors.New("version mismatch")
+
+func (s *Store) GetWithVersion(key string) (interface{}, uint64, error) {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	i, err := sh.lookup(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	i.touch()
+	return i.value(), i.Version, nil
+}
+
+// replaces the value only if the key has the expected version, 0 stands for a key which doesn't exist,
+// returns the new version
+func (s *Store) CompareAndSwap(key string, version uint64, value interface{}, ttl time.Duration) (uint64, error) {
+	encoded, err := encodeValue(value)
+	if err != nil {
+		return 0, err
+	}
+	i := &item{
+		Value:      value,
+		Expiration: s.expiration(ttl),
+	}
+
+	sh := s.shard(key)
+	err = sh.locked(func() error {
+		if err := sh.compareAndSwap(key, version, i); err != nil {
+			return err
+		}
+		s.wal.appendEncoded(key, *i, encoded)
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	s.evict()
+	s.updated()
+	return i.Version, nil
+}
+
+func (sh *shard) compareAndSwap(key string, version uint64, i *item) error {
+	var current uint64
+	if old, err := sh.lookup(key); err == nil {
+		current = old.Version
+	}
+	if current != version {
+		return fmt.Errorf("%w for key '%v'", ErrVersionMismatch, key)
+	}
+	sh.set(key, i)
+	return nil
+}
@@ -0,0 +1,88 @@
+package store_test
+
+import (
+	"errors"
+	"github.com/baratov/golang-playground/store"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetWithVersion(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Minute)
+	_, v1, err := s.GetWithVersion("someKey")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+
+	s.Update("someKey", 234, time.Minute)
+	val, v2, err := s.GetWithVersion("someKey")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if val != 234 {
+		t.Errorf("Expected value is 234, but found %v", val)
+	}
+	if v2 <= v1 {
+		t.Errorf("Expected version greater than %v, but found %v", v1, v2)
+	}
+
+	// a key created again doesn't start over
+	s.Delete("someKey")
+	s.Set("someKey", 345, time.Minute)
+	if _, v3, _ := s.GetWithVersion("someKey"); v3 <= v2 {
+		t.Errorf("Expected version greater than %v, but found %v", v2, v3)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	s := newStore(t)
+
+	v1, err := s.CompareAndSwap("someKey", 0, 123, time.Minute)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	v2, err := s.CompareAndSwap("someKey", v1, 234, time.Minute)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+
+	_, err = s.CompareAndSwap("someKey", v1, 345, time.Minute)
+	expected := "version mismatch for key 'someKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+	if !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("Expected error to be ErrVersionMismatch, but found %v", err)
+	}
+
+	val, version, _ := s.GetWithVersion("someKey")
+	if val != 234 || version != v2 {
+		t.Errorf("Expected value 234 of version %v, but found %v of version %v", v2, val, version)
+	}
+}
+
+func TestCompareAndSwap_Concurrent(t *testing.T) {
+	s := newStore(t)
+	s.Set("counter", 0, time.Minute)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 10; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10; {
+				val, version, _ := s.GetWithVersion("counter")
+				if _, err := s.CompareAndSwap("counter", version, val.(int)+1, time.Minute); err == nil {
+					i++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if val, _ := s.Get("counter"); val != 100 {
+		t.Errorf("Expected value is 100, but found %v", val)
+	}
+}
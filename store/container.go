@@ -0,0 +1,155 @@
+package store
+
+import "errors"
+
+// native data types are changed in place under lock of their shard,
+// Get hands out a copy, so the stored value never leaks out of the lock
+
+type container interface {
+	len() int
+	clone() interface{}
+}
+
+// value as it is returned to a caller, bitmaps are changed in place as well
+func (item *item) value() interface{} {
+	switch v := item.Value.(type) {
+	case container:
+		return v.clone()
+	case []byte:
+		return append([]byte(nil), v...)
+	}
+	return item.Value
+}
+
+// a change of a container in place, it goes to the log instead of the whole value,
+// so the log grows with the size of changes and not with the size of values
+type mutation interface {
+	apply(key string, val interface{}) (int64, error)
+}
+
+// returned by a mutation which left the value as it was, there is no new version, event or log record then
+var errUnchanged = errors.New("value is not changed")
+
+// mutations of values which are replaced rather than changed in place, as bitmaps which grow by append
+type replacer interface {
+	replaced() interface{}
+}
+
+// mutations which depend on the time resolve it in apply and are replayed as they were resolved
+type replayer interface {
+	replay(val interface{})
+}
+
+// applies fn to the value of the key in place under write lock, a missing key is created by create
+// unless it is nil, fn returns how the size of the value has changed, the key is deleted once it turns empty,
+// the whole value goes to the log, merges which rewrite all of it anyway are fine with it
+func (s *Store) change(key string, create func() interface{}, fn func(val interface{}) (int64, error)) error {
+	return s.changeLogged(key, create, fn, nil)
+}
+
+// changes the value as change does, but only the mutation goes to the log, a created key goes
+// as its initial value along with the mutation
+func (s *Store) mutate(key string, create func() interface{}, m mutation) error {
+	if err := encodableChange(m); err != nil {
+		return err
+	}
+	return s.changeLogged(key, create, func(val interface{}) (int64, error) {
+		return m.apply(key, val)
+	}, m)
+}
+
+// logs m for changes in place, the whole value if m is nil
+func (s *Store) changeLogged(key string, create func() interface{}, fn func(val interface{}) (int64, error), m mutation) error {
+	sh := s.shard(key)
+	err := sh.locked(func() error {
+		i, err := sh.lookup(key)
+		created := false
+		if err != nil && create != nil {
+			i, err = &item{Value: create(), Expiration: s.expiration(NoExpiration)}, nil
+			created = true
+		}
+		if err != nil {
+			return err
+		}
+
+		delta, err := fn(i.Value)
+		if err == errUnchanged && created {
+			err = nil // a new key is stored anyway
+		}
+		if err != nil {
+			return err
+		}
+		if r, ok := m.(replacer); ok {
+			i.Value = r.replaced()
+		}
+		if c, ok := i.Value.(container); ok && c.len() == 0 {
+			if !created {
+				sh.delete(key)
+				s.wal.append(opDelete, key, item{})
+			}
+		} else if created && m != nil {
+			sh.set(key, i)
+			s.wal.appendBatch([]walRecord{
+				{Op: opSet, Key: key, Item: item{Value: create(), Expiration: i.Expiration}},
+				{Op: opChange, Key: key, Item: item{Expiration: i.Expiration, Version: i.Version}, Change: m},
+			})
+		} else if created {
+			sh.set(key, i)
+			s.wal.append(opSet, key, *i)
+		} else if m != nil {
+			sh.changed(i, i.size+delta, m)
+			s.wal.appendChange(key, *i, m)
+		} else {
+			sh.changed(i, i.size+delta, nil)
+			s.wal.append(opSet, key, *i)
+		}
+		return nil
+	})
+
+	if err == errUnchanged {
+		return nil
+	}
+	if err == nil {
+		s.evict()
+		s.updated()
+	}
+	return err
+}
+
+// runs fn on the value of the key under read lock, fn gets nil for a missing key
+func (s *Store) read(key string, fn func(val interface{}) error) error {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	i, err := sh.lookup(key)
+	if err != nil {
+		return fn(nil)
+	}
+	i.touch()
+	return fn(i.Value)
+}
+
+// runs fn on values of the keys under read locks of their shards, taken in order of shards
+// as Txn does, fn gets nil for missing keys
+func (s *Store) readMany(keys []string, fn func(vals []interface{}) error) error {
+	locked := make([]bool, len(s.shards))
+	for _, key := range keys {
+		locked[s.shardIndex(key)] = true
+	}
+	for n, sh := range s.shards {
+		if locked[n] {
+			sh.mu.RLock()
+			defer sh.mu.RUnlock()
+		}
+	}
+
+	vals := make([]interface{}, len(keys))
+	for n, key := range keys {
+		if i, err := s.shard(key).lookup(key); err == nil {
+			i.touch()
+			vals[n] = i.Value
+		}
+	}
+	return fn(vals)
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// atomic counters over numeric values or strings holding numbers,
+// type of the stored value is kept, so do the expiration and the rest of the item
+
+const errOverflowFmt = "increment or decrement would overflow key '%v'"
+
+func (s *Store) Incr(key string) (int64, error) {
+	return s.IncrBy(key, 1)
+}
+
+func (s *Store) Decr(key string) (int64, error) {
+	return s.IncrBy(key, -1)
+}
+
+// missing key is created as persistent int64
+func (s *Store) IncrBy(key string, delta int64) (int64, error) {
+	var result int64
+	err := s.modify(key, int64(0), func(val interface{}) (interface{}, error) {
+		var (
+			changed interface{}
+			err     error
+		)
+		changed, result, err = incrBy(key, val, delta)
+		return changed, err
+	})
+	return result, err
+}
+
+// missing key is created as persistent float64, integers turn into float64
+func (s *Store) IncrByFloat(key string, delta float64) (float64, error) {
+	var result float64
+	err := s.modify(key, float64(0), func(val interface{}) (interface{}, error) {
+		var (
+			changed interface{}
+			err     error
+		)
+		changed, result, err = incrByFloat(key, val, delta)
+		return changed, err
+	})
+	return result, err
+}
+
+// replaces value of the key in place by fn under lock, missing key starts with the initial value
+// and is set only once fn succeeds, with the default ttl of the store
+func (s *Store) modify(key string, initial interface{}, fn func(interface{}) (interface{}, error)) error {
+	sh := s.shard(key)
+	err := sh.locked(func() error {
+		i, err := sh.lookup(key)
+		current := initial
+		if err == nil {
+			current = i.Value
+		}
+		val, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if i == nil {
+			i = &item{Value: val, Expiration: s.expiration(NoExpiration)}
+			sh.set(key, i)
+		} else {
+			i.Value = val
+			sh.changed(i, sizeOf(key, val), nil)
+		}
+		s.wal.append(opSet, key, *i)
+		return nil
+	})
+
+	if err == nil {
+		s.evict()
+		s.updated()
+	}
+	return err
+}
+
+func incrBy(key string, val interface{}, delta int64) (interface{}, int64, error) {
+	v := reflect.ValueOf(val)
+	if !v.IsValid() {
+		return nil, 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	result := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int() + delta
+		if (delta > 0 && n < v.Int()) || (delta < 0 && n > v.Int()) || result.OverflowInt(n) {
+			return nil, 0, fmt.Errorf(errOverflowFmt, key)
+		}
+		result.SetInt(n)
+		return result.Interface(), n, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, 0, fmt.Errorf(errOverflowFmt, key)
+		}
+		n := int64(v.Uint()) + delta
+		if n < 0 || (delta > 0 && n < int64(v.Uint())) || result.OverflowUint(uint64(n)) {
+			return nil, 0, fmt.Errorf(errOverflowFmt, key)
+		}
+		result.SetUint(uint64(n))
+		return result.Interface(), n, nil
+	case reflect.Float32, reflect.Float64: // json numbers
+		f := v.Float()
+		if f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+			return nil, 0, fmt.Errorf(errWrongTypeFmt, key)
+		}
+		n := int64(f) + delta
+		result.SetFloat(float64(n))
+		return result.Interface(), n, nil
+	case reflect.String:
+		old, err := strconv.ParseInt(v.String(), 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf(errWrongTypeFmt, key)
+		}
+		n := old + delta
+		if (delta > 0 && n < old) || (delta < 0 && n > old) {
+			return nil, 0, fmt.Errorf(errOverflowFmt, key)
+		}
+		return strconv.FormatInt(n, 10), n, nil
+	default:
+		return nil, 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+}
+
+func incrByFloat(key string, val interface{}, delta float64) (interface{}, float64, error) {
+	v := reflect.ValueOf(val)
+	var f float64
+	switch v.Kind() { // Invalid for nil goes to default
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = v.Float() + delta
+		result := reflect.New(v.Type()).Elem()
+		if math.IsInf(f, 0) || math.IsNaN(f) || result.OverflowFloat(f) {
+			return nil, 0, fmt.Errorf(errOverflowFmt, key)
+		}
+		result.SetFloat(f)
+		return result.Interface(), f, nil
+	case reflect.String:
+		old, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf(errWrongTypeFmt, key)
+		}
+		f = old + delta
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, 0, fmt.Errorf(errOverflowFmt, key)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), f, nil
+	default:
+		return nil, 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+
+	f += delta
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, 0, fmt.Errorf(errOverflowFmt, key)
+	}
+	return f, f, nil
+}
@@ -0,0 +1,139 @@
+package store_test
+
+import (
+	"github.com/baratov/golang-playground/store"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIncrBy(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 10, time.Minute)
+
+	n, err := s.IncrBy("someKey", 5)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != 15 {
+		t.Errorf("Expected value is 15, but found %v", n)
+	}
+	if val, _ := s.Get("someKey"); val != 15 { // type of the value is kept
+		t.Errorf("Expected value is int 15, but found %T %v", val, val)
+	}
+	if ttl, _ := s.TTL("someKey"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected ttl to be kept, but found %v", ttl)
+	}
+}
+
+func TestIncrBy_NonExistingKey(t *testing.T) {
+	s := newStore(t)
+
+	n, err := s.Decr("someKey")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != -1 {
+		t.Errorf("Expected value is -1, but found %v", n)
+	}
+	if ttl, _ := s.TTL("someKey"); ttl != store.NoExpiration {
+		t.Errorf("Expected ttl is %v, but found %v", store.NoExpiration, ttl)
+	}
+}
+
+// a new counter is a single set which gets the default ttl
+func TestIncrBy_DefaultTTL(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithDefaultTTL(time.Minute),
+	)
+	defer s.Stop()
+	w := s.Watch("", 10)
+	defer w.Close()
+
+	s.Incr("someKey")
+	if e := <-w.C; e.Type != store.EventSet || e.Value != int64(1) {
+		t.Errorf("Expected set of 1, but found %v", e)
+	}
+	if len(w.C) != 0 {
+		t.Errorf("Expected a single event, but found %v more", len(w.C))
+	}
+	if ttl, _ := s.TTL("someKey"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected ttl about %v, but found %v", time.Minute, ttl)
+	}
+}
+
+func TestIncrBy_NumericValues(t *testing.T) {
+	s := newStore(t)
+	s.Set("string", "41", time.Minute)
+	s.Set("json", float64(41), time.Minute)
+
+	for key, expected := range map[string]interface{}{"string": "42", "json": float64(42)} {
+		if _, err := s.Incr(key); err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if val, _ := s.Get(key); val != expected {
+			t.Errorf("Expected value is %T %v, but found %T %v", expected, expected, val, val)
+		}
+	}
+}
+
+func TestIncrBy_WrongType(t *testing.T) {
+	s := newStore(t)
+	s.Set("string", "some_string_value", time.Minute)
+	s.Set("float", 1.5, time.Minute)
+
+	for _, key := range []string{"string", "float"} {
+		_, err := s.IncrBy(key, 1)
+		expected := "wrong type for key '" + key + "'"
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected error is %s, but found %v", expected, err)
+		}
+	}
+}
+
+func TestIncrBy_Overflow(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", int64(math.MaxInt64), time.Minute)
+
+	_, err := s.Incr("someKey")
+	expected := "increment or decrement would overflow key 'someKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestIncrByFloat(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 10, time.Minute)
+
+	f, err := s.IncrByFloat("someKey", 0.5)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if f != 10.5 {
+		t.Errorf("Expected value is 10.5, but found %v", f)
+	}
+	if val, _ := s.Get("someKey"); val != 10.5 {
+		t.Errorf("Expected value is 10.5, but found %v", val)
+	}
+}
+
+func TestIncrBy_Concurrent(t *testing.T) {
+	s := newStore(t)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 100; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Incr("counter")
+		}()
+	}
+	wg.Wait()
+
+	if val, _ := s.Get("counter"); val != int64(100) {
+		t.Errorf("Expected value is 100, but found %v", val)
+	}
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"math/rand"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// approximated eviction as in redis: a few random keys are sampled and the worst one is evicted,
+// keeps Get under read lock as only atomic counters are touched there
+
+const (
+	evictionSamples = 5
+	itemOverhead    = 64 // map entry, item struct and expiration, roughly
+)
+
+// Entry is what eviction policy knows about a stored key
+type Entry struct {
+	Key        string
+	Expiration time.Time
+	LastAccess time.Time
+	Hits       uint64
+}
+
+// EvictionPolicy reports whether a should be evicted before b
+type EvictionPolicy func(a, b Entry) bool
+
+// least recently used
+func EvictLRU(a, b Entry) bool {
+	return a.LastAccess.Before(b.LastAccess)
+}
+
+// least frequently used, the least recently used of equals
+func EvictLFU(a, b Entry) bool {
+	if a.Hits == b.Hits {
+		return EvictLRU(a, b)
+	}
+	return a.Hits < b.Hits
+}
+
+// keeps the first sampled key, map iteration order is random enough for that
+func EvictRandom(a, b Entry) bool {
+	return false
+}
+
+// the closest to expiration first, persistent keys last
+func EvictVolatileTTL(a, b Entry) bool {
+	if a.Expiration.IsZero() || b.Expiration.IsZero() {
+		return !a.Expiration.IsZero()
+	}
+	return a.Expiration.Before(b.Expiration)
+}
+
+func WithMaxItems(n int) setting {
+	return func(s *Store) {
+		s.maxItems = n
+	}
+}
+
+func WithMaxBytes(n int64) setting {
+	return func(s *Store) {
+		s.maxBytes = n
+	}
+}
+
+func WithEvictionPolicy(policy EvictionPolicy) setting {
+	return func(s *Store) {
+		s.evictionPolicy = policy
+	}
+}
+
+// should be called after the store grew, without holding any shard lock
+// as victims are sampled across shards
+func (s *Store) evict() {
+	for s.overLimit() {
+		victim, ok := s.victim()
+		if !ok {
+			return
+		}
+
+		sh := s.shard(victim.Key)
+		sh.locked(func() error {
+			if sh.items[victim.Key] == victim.item { // not replaced since it was sampled
+				sh.remove(victim.Key, EventEvict)
+				s.wal.append(opDelete, victim.Key, item{})
+				atomic.AddUint64(&s.usage.evictions, 1)
+			}
+			return nil
+		})
+	}
+}
+
+func (s *Store) overLimit() bool {
+	return (s.maxItems > 0 && s.usage.count() > int64(s.maxItems)) ||
+		(s.maxBytes > 0 && s.usage.size() > s.maxBytes)
+}
+
+type candidate struct {
+	Entry
+	item *item
+}
+
+// samples keys starting from a random shard, never holds more than one shard lock
+func (s *Store) victim() (candidate, bool) {
+	var victim candidate
+	found := false
+	sampled := 0
+	start := rand.Intn(len(s.shards))
+	for n := 0; n < len(s.shards) && sampled < evictionSamples; n++ {
+		sh := s.shards[(start+n)%len(s.shards)]
+		sh.mu.RLock()
+		for key, i := range sh.items {
+			e := i.entry(key)
+			if !found || s.evictionPolicy(e, victim.Entry) {
+				victim = candidate{Entry: e, item: i}
+				found = true
+			}
+			if sampled++; sampled >= evictionSamples {
+				break
+			}
+		}
+		sh.mu.RUnlock()
+	}
+	return victim, found
+}
+
+func (item *item) entry(key string) Entry {
+	return Entry{
+		Key:        key,
+		Expiration: item.Expiration,
+		LastAccess: time.Unix(0, atomic.LoadInt64(&item.lastAccess)),
+		Hits:       atomic.LoadUint64(&item.hits),
+	}
+}
+
+// rough estimation of memory taken by a key and its value
+// values which know their size better than reflection does
+type sizer interface {
+	size() int64
+}
+
+func sizeOf(key string, value interface{}) int64 {
+	if v, ok := value.(sizer); ok {
+		return itemOverhead + int64(len(key)) + v.size()
+	}
+	return itemOverhead + int64(len(key)) + sizeOfValue(reflect.ValueOf(value))
+}
+
+func sizeOfValue(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return int64(v.Len())
+		}
+		var size int64
+		for i := 0; i < v.Len(); i++ {
+			size += sizeOfValue(v.Index(i))
+		}
+		return size
+	case reflect.Map:
+		var size int64
+		iter := v.MapRange()
+		for iter.Next() {
+			size += sizeOfValue(iter.Key()) + sizeOfValue(iter.Value())
+		}
+		return size
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return sizeOfValue(v.Elem())
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += sizeOfValue(v.Field(i))
+		}
+		return size
+	default:
+		return int64(v.Type().Size())
+	}
+}
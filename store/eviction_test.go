@@ -0,0 +1,93 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"testing"
+	"time"
+)
+
+func TestMaxItems_LRU(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithMaxItems(3),
+		store.WithEvictionPolicy(store.EvictLRU),
+	)
+	defer s.Stop()
+	s.Set("key1", 1, time.Minute)
+	s.Set("key2", 2, time.Minute)
+	s.Set("key3", 3, time.Minute)
+	s.Get("key1")
+	s.Get("key3")
+	s.Set("key4", 4, time.Minute)
+
+	if _, err := s.Get("key2"); err == nil {
+		t.Errorf("Expected least recently used key2 to be evicted")
+	}
+	for _, key := range []string{"key1", "key3", "key4"} {
+		if _, err := s.Get(key); err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+	}
+	if stats := s.Stats(); stats.Items != 3 || stats.Evictions != 1 {
+		t.Errorf("Expected 3 items and 1 eviction, but found %+v", stats)
+	}
+}
+
+func TestMaxItems_LFU(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithMaxItems(2),
+		store.WithEvictionPolicy(store.EvictLFU),
+	)
+	defer s.Stop()
+	s.Set("key1", 1, time.Minute)
+	s.Set("key2", 2, time.Minute)
+	for i := 0; i < 3; i++ {
+		s.Get("key1")
+	}
+	s.Set("key3", 3, time.Minute)
+
+	if _, err := s.Get("key2"); err == nil {
+		t.Errorf("Expected least frequently used key2 to be evicted")
+	}
+	if _, err := s.Get("key1"); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+}
+
+func TestMaxItems_VolatileTTL(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithMaxItems(2),
+		store.WithEvictionPolicy(store.EvictVolatileTTL),
+	)
+	defer s.Stop()
+	s.Set("key1", 1, time.Hour)
+	s.Set("key2", 2, time.Minute)
+	s.Set("key3", 3, time.Hour)
+
+	if _, err := s.Get("key2"); err == nil {
+		t.Errorf("Expected the closest to expiration key2 to be evicted")
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithMaxBytes(1024),
+		store.WithEvictionPolicy(store.EvictRandom),
+	)
+	defer s.Stop()
+	for i := 0; i < 100; i++ {
+		s.Set(fmt.Sprintf("key%d", i), "some_string_value", time.Minute)
+	}
+
+	stats := s.Stats()
+	if stats.Bytes > 1024 {
+		t.Errorf("Expected at most 1024 bytes, but found %v", stats.Bytes)
+	}
+	if stats.Evictions == 0 || uint64(stats.Items)+stats.Evictions != 100 {
+		t.Errorf("Expected evicted and kept items to sum up to 100, but found %+v", stats)
+	}
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// items ordered by expiration, so each tick touches only the keys which are actually expired,
+// get still checks expiration itself for the keys waiting for the next tick
+
+type expirationHeap []*item
+
+func (h expirationHeap) Len() int {
+	return len(h)
+}
+
+func (h expirationHeap) Less(i, j int) bool {
+	return h[i].Expiration.Before(h[j].Expiration)
+}
+
+func (h expirationHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expirationHeap) Push(x interface{}) {
+	i := x.(*item)
+	i.index = len(*h)
+	*h = append(*h, i)
+}
+
+func (h *expirationHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	i := old[n-1]
+	old[n-1] = nil
+	i.index = -1
+	*h = old[:n-1]
+	return i
+}
+
+// fields of hashes ordered by expiration, the same way as items
+type fieldExpiration struct {
+	key   string
+	field string
+	at    time.Time
+	index int // position in the heap
+}
+
+type fieldHeap []*fieldExpiration
+
+func (h fieldHeap) Len() int {
+	return len(h)
+}
+
+func (h fieldHeap) Less(i, j int) bool {
+	return h[i].at.Before(h[j].at)
+}
+
+func (h fieldHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *fieldHeap) Push(x interface{}) {
+	fe := x.(*fieldExpiration)
+	fe.index = len(*h)
+	*h = append(*h, fe)
+}
+
+func (h *fieldHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	fe := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return fe
+}
+
+func (s *Store) runExpiration() {
+	c := time.Tick(s.expirationInterval)
+	for {
+		select {
+		case <-c:
+			s.expire()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// shards are locked one by one, so readers of other shards are not stalled
+func (s *Store) expire() {
+	for _, sh := range s.shards {
+		sh.expire()
+	}
+}
+
+func (sh *shard) expire() {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	for len(sh.expiring) > 0 && sh.expiring[0].isExpired() {
+		sh.remove(sh.expiring[0].key, EventExpire)
+		atomic.AddUint64(&sh.usage.expirations, 1)
+	}
+
+	// expired fields are not logged, they are skipped by reads after a restore as well
+	now := time.Now()
+	for len(sh.fields) > 0 && now.After(sh.fields[0].at) {
+		fe := sh.fields[0]
+		sh.untrackField(fe.key, fe.field)
+
+		i := sh.items[fe.key]
+		h := i.Value.(*Hash)
+		delta := h.remove(fe.field)
+		h.touched = nil // untracked already
+		atomic.AddInt64(&sh.usage.bytes, delta)
+		i.size += delta
+		if len(h.Fields) == 0 {
+			sh.remove(fe.key, EventExpire)
+			atomic.AddUint64(&sh.usage.expirations, 1)
+		}
+	}
+}
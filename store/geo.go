@@ -0,0 +1,243 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// geo index is a sorted set scored by 52-bit geohash as in redis, so members close on the map
+// are close in the set, search looks into the cell of the center and its 8 neighbours
+// at the precision where a cell is not smaller than the search area, distances are in meters
+
+const (
+	errGeoFmt         = "invalid longitude,latitude pair %v,%v"
+	errGeoQuery       = "either radius or width and height of the box are required"
+	geoStep           = 26 // bits of each coordinate
+	geoLatMin         = -85.05112878
+	geoLatMax         = 85.05112878
+	geoLonMin         = -180.0
+	geoLonMax         = 180.0
+	earthRadius       = 6372797.560856
+	metersPerLatitude = math.Pi * earthRadius / 180
+)
+
+type GeoLocation struct {
+	Member    string  `json:"member"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+	Distance  float64 `json:"distance,omitempty"` // to the center of GeoSearch
+}
+
+// center is the member if it is set, search is by radius if it is set and by box otherwise
+type GeoQuery struct {
+	Member    string
+	Longitude float64
+	Latitude  float64
+	Radius    float64
+	Width     float64
+	Height    float64
+	Count     int // 0 returns all found members
+}
+
+// returns number of members which were not in the index before, positions of the rest are updated
+func (s *Store) GeoAdd(key string, locations ...GeoLocation) (int, error) {
+	members := make([]ScoredMember, len(locations))
+	for n, l := range locations {
+		if !validGeo(l.Longitude, l.Latitude) {
+			return 0, fmt.Errorf(errGeoFmt, l.Longitude, l.Latitude)
+		}
+		members[n] = ScoredMember{Member: l.Member, Score: float64(geoEncode(l.Longitude, l.Latitude))}
+	}
+	return s.ZAdd(key, members...)
+}
+
+// position is the center of the geohash cell, it differs from the added one by less than a meter
+func (s *Store) GeoPos(key, member string) (GeoLocation, error) {
+	score, err := s.ZScore(key, member)
+	if err != nil {
+		return GeoLocation{}, err
+	}
+	lon, lat := geoDecode(uint64(score))
+	return GeoLocation{Member: member, Longitude: lon, Latitude: lat}, nil
+}
+
+func (s *Store) GeoDist(key, member1, member2 string) (float64, error) {
+	var dist float64
+	err := s.read(key, func(val interface{}) error {
+		z, err := asZSet(key, val)
+		if err != nil {
+			return err
+		}
+		lon1, lat1, err := z.geoPos(member1)
+		if err != nil {
+			return err
+		}
+		lon2, lat2, err := z.geoPos(member2)
+		if err != nil {
+			return err
+		}
+		dist = geoDistance(lon1, lat1, lon2, lat2)
+		return nil
+	})
+	return dist, err
+}
+
+// members within the radius or the box around the center, the nearest first
+func (s *Store) GeoSearch(key string, q GeoQuery) ([]GeoLocation, error) {
+	if q.Radius <= 0 && (q.Width <= 0 || q.Height <= 0) {
+		return nil, fmt.Errorf(errGeoQuery)
+	}
+
+	result := []GeoLocation{}
+	err := s.read(key, func(val interface{}) error {
+		z, err := asZSet(key, val)
+		if err != nil {
+			return err
+		}
+		if q.Member != "" {
+			if q.Longitude, q.Latitude, err = z.geoPos(q.Member); err != nil {
+				return err
+			}
+		} else if !validGeo(q.Longitude, q.Latitude) {
+			return fmt.Errorf(errGeoFmt, q.Longitude, q.Latitude)
+		}
+
+		for _, r := range geoRanges(q) {
+			for x := z.list.firstFrom(r[0]); x != nil && x.score < r[1]; x = x.levels[0].next {
+				lon, lat := geoDecode(uint64(x.score))
+				if dist, ok := q.contains(lon, lat); ok {
+					result = append(result, GeoLocation{Member: x.member, Longitude: lon, Latitude: lat, Distance: dist})
+				}
+			}
+		}
+		return nil
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Distance < result[j].Distance
+	})
+	if q.Count > 0 && len(result) > q.Count {
+		result = result[:q.Count]
+	}
+	return result, err
+}
+
+func (z *ZSet) geoPos(member string) (float64, float64, error) {
+	score, ok := z.scores[member]
+	if !ok {
+		return 0, 0, fmt.Errorf(errKeyNotFoundFmt, member)
+	}
+	lon, lat := geoDecode(uint64(score))
+	return lon, lat, nil
+}
+
+// returns distance to the center if the point is in the area of the query
+func (q GeoQuery) contains(lon, lat float64) (float64, bool) {
+	dist := geoDistance(q.Longitude, q.Latitude, lon, lat)
+	if q.Radius > 0 {
+		return dist, dist <= q.Radius
+	}
+	// offsets along the parallel and the meridian of the center
+	dy := geoDistance(q.Longitude, q.Latitude, q.Longitude, lat)
+	dx := geoDistance(q.Longitude, lat, lon, lat)
+	return dist, dy <= q.Height/2 && dx <= q.Width/2
+}
+
+// score ranges [from, to) of the cell of the center and its neighbours
+func geoRanges(q GeoQuery) [][2]float64 {
+	halfLat, halfLon := q.Radius, q.Radius
+	if q.Radius <= 0 {
+		halfLat, halfLon = q.Height/2, q.Width/2
+	}
+
+	degLat := halfLat / metersPerLatitude
+	edge := math.Min(math.Abs(q.Latitude)+degLat, 89.9) // cells are the narrowest at the edge closer to a pole
+	degLon := halfLon / (metersPerLatitude * math.Cos(edge*math.Pi/180))
+
+	step := geoStep
+	for step > 1 && ((geoLatMax-geoLatMin)/float64(uint64(1)<<uint(step)) < degLat ||
+		(geoLonMax-geoLonMin)/float64(uint64(1)<<uint(step)) < degLon) {
+		step--
+	}
+
+	cells := uint64(1) << uint(step)
+	latIdx, lonIdx := geoCell(q.Longitude, q.Latitude, step)
+	shift := uint(2 * (geoStep - step))
+	seen := make(map[uint64]bool)
+	var ranges [][2]float64
+	for dLat := -1; dLat <= 1; dLat++ {
+		lat := int64(latIdx) + int64(dLat)
+		if lat < 0 || lat >= int64(cells) {
+			continue
+		}
+		for dLon := -1; dLon <= 1; dLon++ {
+			lon := (int64(lonIdx) + int64(dLon) + int64(cells)) % int64(cells) // longitude wraps around
+			hash := interleave(uint64(lat), uint64(lon))
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			ranges = append(ranges, [2]float64{float64(hash << shift), float64((hash + 1) << shift)})
+		}
+	}
+	return ranges
+}
+
+func validGeo(lon, lat float64) bool {
+	return lon >= geoLonMin && lon <= geoLonMax && lat >= geoLatMin && lat <= geoLatMax
+}
+
+// indexes of the cell at the precision of step bits per coordinate
+func geoCell(lon, lat float64, step int) (uint64, uint64) {
+	cells := float64(uint64(1) << uint(step))
+	latIdx := uint64((lat - geoLatMin) / (geoLatMax - geoLatMin) * cells)
+	lonIdx := uint64((lon - geoLonMin) / (geoLonMax - geoLonMin) * cells)
+	last := uint64(cells) - 1 // for the upper bounds themselves
+	if latIdx > last {
+		latIdx = last
+	}
+	if lonIdx > last {
+		lonIdx = last
+	}
+	return latIdx, lonIdx
+}
+
+func geoEncode(lon, lat float64) uint64 {
+	latIdx, lonIdx := geoCell(lon, lat, geoStep)
+	return interleave(latIdx, lonIdx)
+}
+
+func geoDecode(hash uint64) (float64, float64) {
+	latIdx, lonIdx := deinterleave(hash)
+	cells := float64(uint64(1) << geoStep)
+	lat := geoLatMin + (float64(latIdx)+0.5)*(geoLatMax-geoLatMin)/cells
+	lon := geoLonMin + (float64(lonIdx)+0.5)*(geoLonMax-geoLonMin)/cells
+	return lon, lat
+}
+
+// bits of latitude go to even positions and bits of longitude to odd ones
+func interleave(lat, lon uint64) uint64 {
+	var hash uint64
+	for n := uint(0); n < geoStep; n++ {
+		hash |= (lat>>n&1)<<(2*n) | (lon>>n&1)<<(2*n+1)
+	}
+	return hash
+}
+
+func deinterleave(hash uint64) (uint64, uint64) {
+	var lat, lon uint64
+	for n := uint(0); n < geoStep; n++ {
+		lat |= (hash >> (2 * n) & 1) << n
+		lon |= (hash >> (2*n + 1) & 1) << n
+	}
+	return lat, lon
+}
+
+// haversine
+func geoDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	lat1r, lat2r := lat1*math.Pi/180, lat2*math.Pi/180
+	u := math.Sin((lat2r - lat1r) / 2)
+	v := math.Sin((lon2 - lon1) * math.Pi / 180 / 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(u*u+math.Cos(lat1r)*math.Cos(lat2r)*v*v))
+}
@@ -0,0 +1,144 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// from the redis documentation
+var sicily = []store.GeoLocation{
+	{Member: "Palermo", Longitude: 13.361389, Latitude: 38.115556},
+	{Member: "Catania", Longitude: 15.087269, Latitude: 37.502669},
+	{Member: "Agrigento", Longitude: 13.583333, Latitude: 37.316667},
+}
+
+func TestGeoAddGeoPos(t *testing.T) {
+	s := newStore(t)
+
+	n, err := s.GeoAdd("sicily", sicily...)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 added members, but found %v", n)
+	}
+
+	pos, err := s.GeoPos("sicily", "Palermo")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if math.Abs(pos.Longitude-13.361389) > 1e-5 || math.Abs(pos.Latitude-38.115556) > 1e-5 {
+		t.Errorf("Expected position is 13.361389,38.115556, but found %v,%v", pos.Longitude, pos.Latitude)
+	}
+
+	_, err = s.GeoAdd("sicily", store.GeoLocation{Member: "Nowhere", Longitude: 0, Latitude: 89})
+	expected := "invalid longitude,latitude pair 0,89"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestGeoDist(t *testing.T) {
+	s := newStore(t)
+	s.GeoAdd("sicily", sicily...)
+
+	dist, err := s.GeoDist("sicily", "Palermo", "Catania")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if math.Abs(dist-166274.15) > 1 {
+		t.Errorf("Expected distance is 166274.15, but found %v", dist)
+	}
+
+	_, err = s.GeoDist("sicily", "Palermo", "Rome")
+	expected := "key 'Rome' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestGeoSearch(t *testing.T) {
+	s := newStore(t)
+	s.GeoAdd("sicily", sicily...)
+
+	found, err := s.GeoSearch("sicily", store.GeoQuery{Longitude: 15, Latitude: 37, Radius: 150000})
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if len(found) != 2 || found[0].Member != "Catania" || found[1].Member != "Agrigento" {
+		t.Errorf("Expected Catania and Agrigento, but found %v", found)
+	}
+	if math.Abs(found[0].Distance-56441) > 1 {
+		t.Errorf("Expected distance to Catania is 56441, but found %v", found[0].Distance)
+	}
+
+	found, _ = s.GeoSearch("sicily", store.GeoQuery{Member: "Palermo", Width: 100000, Height: 200000})
+	if len(found) != 2 || found[0].Member != "Palermo" || found[1].Member != "Agrigento" {
+		t.Errorf("Expected Palermo and Agrigento, but found %v", found)
+	}
+
+	found, _ = s.GeoSearch("sicily", store.GeoQuery{Longitude: 15, Latitude: 37, Radius: 400000, Count: 1})
+	if len(found) != 1 || found[0].Member != "Catania" {
+		t.Errorf("Expected Catania, but found %v", found)
+	}
+
+	if _, err = s.GeoSearch("sicily", store.GeoQuery{Longitude: 15, Latitude: 37}); err == nil {
+		t.Errorf("Expected error without radius and box")
+	}
+}
+
+// search through the cells finds the same members as the check of every one of them
+func TestGeoSearch_Random(t *testing.T) {
+	s := newStore(t)
+	r := rand.New(rand.NewSource(1))
+	var locations []store.GeoLocation
+	for n := 0; n < 2000; n++ {
+		locations = append(locations, store.GeoLocation{
+			Member:    fmt.Sprintf("m%d", n),
+			Longitude: r.Float64()*360 - 180,
+			Latitude:  r.Float64()*160 - 80,
+		})
+	}
+	s.GeoAdd("points", locations...)
+
+	for n := 0; n < 50; n++ {
+		center := locations[r.Intn(len(locations))]
+		radius := math.Pow(10, 4+r.Float64()*3) // from 10km to 10000km
+		found, _ := s.GeoSearch("points", store.GeoQuery{Member: center.Member, Radius: radius})
+
+		expected := 0
+		for _, l := range locations {
+			if dist, _ := s.GeoDist("points", center.Member, l.Member); dist <= radius {
+				expected++
+			}
+		}
+		if len(found) != expected {
+			t.Errorf("Expected %v members within %vm of %v, but found %v", expected, radius, center.Member, len(found))
+		}
+	}
+}
+
+func TestGeo_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.GeoAdd("sicily", sicily[0])
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.GeoAdd("sicily", sicily[1])
+	if dist, _ := r.GeoDist("sicily", "Palermo", "Catania"); math.Abs(dist-166274.15) > 1 {
+		t.Errorf("Expected distance is 166274.15, but found %v", dist)
+	}
+
+	//teardown
+	r.Stop()
+}
@@ -0,0 +1,403 @@
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Hash is a native map of fields, created by HSet or HIncrBy and removed once it is empty,
+// fields can expire on their own, reads skip expired fields and writes or expire drop them
+
+const fieldExpirationSize = 24
+
+func init() {
+	gob.Register(&Hash{})
+	gob.Register(&hashSet{})
+	gob.Register(&hashDel{})
+	gob.Register(&hashIncr{})
+	gob.Register(&hashExpire{})
+}
+
+type Hash struct {
+	Fields      map[string]interface{}
+	Expirations map[string]time.Time // only fields with ttl
+	touched     []string             // fields with changed expiration, for the heap of the shard
+}
+
+// number of live fields
+func (h *Hash) len() int {
+	if len(h.Expirations) == 0 {
+		return len(h.Fields)
+	}
+	n := 0
+	for f := range h.Fields {
+		if !h.isExpired(f) {
+			n++
+		}
+	}
+	return n
+}
+
+// live fields
+func (h *Hash) clone() interface{} {
+	fields := make(map[string]interface{}, len(h.Fields))
+	for f, v := range h.Fields {
+		if !h.isExpired(f) {
+			fields[f] = v
+		}
+	}
+	return fields
+}
+
+func (h *Hash) size() int64 {
+	var size int64
+	for f, v := range h.Fields {
+		size += sizeOfField(f, v)
+	}
+	return size + int64(len(h.Expirations))*fieldExpirationSize
+}
+
+func newHash() interface{} {
+	return &Hash{Fields: make(map[string]interface{})}
+}
+
+func (h *Hash) isExpired(field string) bool {
+	t, ok := h.Expirations[field]
+	return ok && time.Now().After(t)
+}
+
+// true if there are fields and all of them expired, checked cheap for hashes with persistent fields
+func (h *Hash) expired() bool {
+	if len(h.Expirations) < len(h.Fields) || len(h.Fields) == 0 {
+		return false
+	}
+	return h.len() == 0
+}
+
+func (h *Hash) get(field string) (interface{}, bool) {
+	v, ok := h.Fields[field]
+	if !ok || h.isExpired(field) {
+		return nil, false
+	}
+	return v, true
+}
+
+// helpers below return how the size of the hash has changed
+
+// keeps expiration of the field
+func (h *Hash) set(field string, value interface{}) int64 {
+	delta := sizeOfField(field, value)
+	if old, ok := h.Fields[field]; ok {
+		delta -= sizeOfField(field, old)
+	}
+	h.Fields[field] = value
+	return delta
+}
+
+func (h *Hash) remove(field string) int64 {
+	old, ok := h.Fields[field]
+	if !ok {
+		return 0
+	}
+	delete(h.Fields, field)
+	return -sizeOfField(field, old) + h.expireAt(field, time.Time{})
+}
+
+// zero time makes the field persistent
+func (h *Hash) expireAt(field string, t time.Time) int64 {
+	_, had := h.Expirations[field]
+	if had || !t.IsZero() {
+		h.touched = append(h.touched, field)
+	}
+	switch {
+	case t.IsZero() && had:
+		delete(h.Expirations, field)
+		return -fieldExpirationSize
+	case t.IsZero():
+		return 0
+	case h.Expirations == nil:
+		h.Expirations = make(map[string]time.Time)
+	}
+	h.Expirations[field] = t
+	if had {
+		return 0
+	}
+	return fieldExpirationSize
+}
+
+// drops expired fields
+func (h *Hash) prune() int64 {
+	var delta int64
+	for f := range h.Expirations {
+		if h.isExpired(f) {
+			delta += h.remove(f)
+		}
+	}
+	return delta
+}
+
+// fn sees expired fields which are not dropped yet, they are dropped once it succeeds
+func changeHash(key string, val interface{}, fn func(h *Hash) (int64, error)) (int64, error) {
+	h, ok := val.(*Hash)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	delta, err := fn(h)
+	if err != nil {
+		return 0, err
+	}
+	return delta + h.prune(), nil
+}
+
+// returns true if the field is new, expiration of the field is removed
+func (s *Store) HSet(key, field string, value interface{}) (bool, error) {
+	return s.HSetEx(key, field, value, NoExpiration)
+}
+
+// sets the field along with its own ttl, returns true if the field is new
+func (s *Store) HSetEx(key, field string, value interface{}, ttl time.Duration) (bool, error) {
+	m := &hashSet{Field: field, Value: value, Expiration: expiration(ttl)}
+	err := s.mutate(key, newHash, m)
+	return m.created, err
+}
+
+type hashSet struct {
+	Field      string
+	Value      interface{}
+	Expiration time.Time // zero for a persistent field
+	created    bool
+}
+
+func (m *hashSet) apply(key string, val interface{}) (int64, error) {
+	return changeHash(key, val, func(h *Hash) (int64, error) {
+		_, found := h.get(m.Field)
+		m.created = !found
+		return h.set(m.Field, m.Value) + h.expireAt(m.Field, m.Expiration), nil
+	})
+}
+
+func (s *Store) HGet(key, field string) (interface{}, error) {
+	var result interface{}
+	err := s.read(key, func(val interface{}) error {
+		if val == nil {
+			return fmt.Errorf(errKeyNotFoundFmt, key)
+		}
+		h, ok := val.(*Hash)
+		if !ok {
+			return fmt.Errorf(errWrongTypeFmt, key)
+		}
+		if result, ok = h.get(field); !ok {
+			return fmt.Errorf(errKeyNotFoundFmt, field)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// returns number of fields which were removed
+func (s *Store) HDel(key string, fields ...string) (int, error) {
+	m := &hashDel{Fields: fields}
+	err := s.mutate(key, nil, m)
+	return m.removed, err
+}
+
+type hashDel struct {
+	Fields  []string
+	removed int
+}
+
+func (m *hashDel) apply(key string, val interface{}) (int64, error) {
+	return changeHash(key, val, func(h *Hash) (int64, error) {
+		var delta int64
+		for _, f := range m.Fields {
+			if _, found := h.get(f); found {
+				m.removed++
+			}
+			delta += h.remove(f)
+		}
+		if m.removed == 0 && delta == 0 { // an expired field which was removed is still a change
+			return 0, errUnchanged
+		}
+		return delta, nil
+	})
+}
+
+// missing key is an empty hash
+func (s *Store) HGetAll(key string) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	err := s.read(key, func(val interface{}) error {
+		h, err := asHash(key, val)
+		if err == nil {
+			fields = h.clone().(map[string]interface{})
+		}
+		return err
+	})
+	return fields, err
+}
+
+// adds delta to the field as IncrBy does to a key, a missing field starts with int64 0,
+// expiration of the field is kept
+func (s *Store) HIncrBy(key, field string, delta int64) (int64, error) {
+	m := &hashIncr{Field: field, Delta: delta}
+	err := s.mutate(key, newHash, m)
+	return m.result, err
+}
+
+// whether the field was expired depends on the time, so it is replayed as the value it produced
+type hashIncr struct {
+	Field      string
+	Delta      int64
+	Value      interface{}
+	Expiration time.Time
+	result     int64
+}
+
+func (m *hashIncr) apply(key string, val interface{}) (int64, error) {
+	return changeHash(key, val, func(h *Hash) (int64, error) {
+		old, found := h.get(m.Field)
+		if !found {
+			old = int64(0)
+		}
+		changed, n, err := incrBy(m.Field, old, m.Delta)
+		if err != nil {
+			return 0, err
+		}
+		m.result, m.Value = n, changed
+		if !found {
+			return h.remove(m.Field) + h.set(m.Field, changed), nil // expired one is not kept
+		}
+		m.Expiration = h.Expirations[m.Field]
+		return h.set(m.Field, changed), nil
+	})
+}
+
+func (m *hashIncr) replay(val interface{}) {
+	if h, ok := val.(*Hash); ok {
+		h.set(m.Field, m.Value)
+		h.expireAt(m.Field, m.Expiration)
+	}
+}
+
+// fields in sorted order
+func (s *Store) HKeys(key string) ([]string, error) {
+	var fields []string
+	err := s.read(key, func(val interface{}) error {
+		h, err := asHash(key, val)
+		if err != nil {
+			return err
+		}
+		fields = make([]string, 0, len(h.Fields))
+		for f := range h.Fields {
+			if !h.isExpired(f) {
+				fields = append(fields, f)
+			}
+		}
+		sort.Strings(fields)
+		return nil
+	})
+	return fields, err
+}
+
+func (s *Store) HLen(key string) (int, error) {
+	n := 0
+	err := s.read(key, func(val interface{}) error {
+		h, err := asHash(key, val)
+		if err == nil {
+			n = h.len()
+		}
+		return err
+	})
+	return n, err
+}
+
+// returns remaining time to live of the field, NoExpiration for persistent fields
+func (s *Store) HTTL(key, field string) (time.Duration, error) {
+	var ttl time.Duration
+	err := s.read(key, func(val interface{}) error {
+		if val == nil {
+			return fmt.Errorf(errKeyNotFoundFmt, key)
+		}
+		h, ok := val.(*Hash)
+		if !ok {
+			return fmt.Errorf(errWrongTypeFmt, key)
+		}
+		if _, ok = h.get(field); !ok {
+			return fmt.Errorf(errKeyNotFoundFmt, field)
+		}
+		if t, ok := h.Expirations[field]; ok {
+			ttl = time.Until(t)
+		}
+		return nil
+	})
+	return ttl, err
+}
+
+func (s *Store) HExpire(key, field string, ttl time.Duration) error {
+	return s.HExpireAt(key, field, time.Now().Add(ttl))
+}
+
+// the field is deleted right away if the time is in the past
+func (s *Store) HExpireAt(key, field string, t time.Time) error {
+	return s.setFieldExpiration(key, field, t)
+}
+
+// removes expiration of the field
+func (s *Store) HPersist(key, field string) error {
+	return s.setFieldExpiration(key, field, time.Time{})
+}
+
+func (s *Store) setFieldExpiration(key, field string, t time.Time) error {
+	return s.mutate(key, nil, &hashExpire{Field: field, At: t})
+}
+
+// the field could expire in between, replay doesn't check whether it is still there
+type hashExpire struct {
+	Field string
+	At    time.Time // zero makes the field persistent
+}
+
+func (m *hashExpire) apply(key string, val interface{}) (int64, error) {
+	return changeHash(key, val, func(h *Hash) (int64, error) {
+		if _, found := h.get(m.Field); !found {
+			return 0, fmt.Errorf(errKeyNotFoundFmt, m.Field)
+		}
+		if !m.At.IsZero() && !m.At.After(time.Now()) {
+			return h.remove(m.Field), nil
+		}
+		return h.expireAt(m.Field, m.At), nil
+	})
+}
+
+func (m *hashExpire) replay(val interface{}) {
+	h, ok := val.(*Hash)
+	if !ok {
+		return
+	}
+	if _, found := h.Fields[m.Field]; !found {
+		return
+	}
+	if !m.At.IsZero() && !m.At.After(time.Now()) {
+		h.remove(m.Field)
+	} else {
+		h.expireAt(m.Field, m.At)
+	}
+}
+
+// missing key is an empty hash
+func asHash(key string, val interface{}) (*Hash, error) {
+	if val == nil {
+		return newHash().(*Hash), nil
+	}
+	h, ok := val.(*Hash)
+	if !ok {
+		return nil, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	return h, nil
+}
+
+func sizeOfField(field string, value interface{}) int64 {
+	return int64(len(field)) + sizeOfValue(reflect.ValueOf(value))
+}
@@ -0,0 +1,222 @@
+package store_test
+
+import (
+	"github.com/baratov/golang-playground/store"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHSetHGet(t *testing.T) {
+	s := newStore(t)
+
+	created, err := s.HSet("user", "name", "alice")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if !created {
+		t.Errorf("Expected the field to be created")
+	}
+	if created, _ = s.HSet("user", "name", "bob"); created {
+		t.Errorf("Expected the field to be updated")
+	}
+	s.HSet("user", "age", 42)
+
+	val, err := s.HGet("user", "name")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if val != "bob" {
+		t.Errorf("Expected value is bob, but found %v", val)
+	}
+	// old way still works
+	if val, _ := s.GetMapEntry("user", "age"); val != 42 {
+		t.Errorf("Expected value is 42, but found %v", val)
+	}
+
+	_, err = s.HGet("user", "nonExisting")
+	expected := "key 'nonExisting' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+	_, err = s.HGet("nonExisting", "name")
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestHDelHGetAll(t *testing.T) {
+	s := newStore(t)
+	s.HSet("user", "name", "alice")
+	s.HSet("user", "city", "berlin")
+	s.HSet("user", "age", 42)
+
+	n, err := s.HDel("user", "city", "nonExisting")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 removed field, but found %v", n)
+	}
+	_, version, _ := s.GetWithVersion("user")
+	s.HDel("user", "nonExisting")
+	if _, v, _ := s.GetWithVersion("user"); v != version {
+		t.Errorf("Expected version %v to stay the same, but found %v", version, v)
+	}
+
+	expected := map[string]interface{}{"name": "alice", "age": 42}
+	if fields, _ := s.HGetAll("user"); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected fields are %v, but found %v", expected, fields)
+	}
+	if keys, _ := s.HKeys("user"); !reflect.DeepEqual(keys, []string{"age", "name"}) {
+		t.Errorf("Expected keys are [age name], but found %v", keys)
+	}
+	if n, _ := s.HLen("user"); n != 2 {
+		t.Errorf("Expected 2 fields, but found %v", n)
+	}
+
+	// empty hash is removed
+	s.HDel("user", "name", "age")
+	if keys := s.Keys(); len(keys) != 0 {
+		t.Errorf("Expected no keys, but found %v", keys)
+	}
+}
+
+func TestHIncrBy(t *testing.T) {
+	s := newStore(t)
+
+	n, err := s.HIncrBy("counters", "visits", 5)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != 5 {
+		t.Errorf("Expected value is 5, but found %v", n)
+	}
+	if n, _ = s.HIncrBy("counters", "visits", -2); n != 3 {
+		t.Errorf("Expected value is 3, but found %v", n)
+	}
+
+	s.HSet("counters", "name", "alice")
+	_, err = s.HIncrBy("counters", "name", 1)
+	expected := "wrong type for key 'name'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestHash_WrongType(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Minute)
+
+	_, err := s.HSet("someKey", "field", 1)
+	expected := "wrong type for key 'someKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestHash_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.HSet("user", "name", "alice")
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.HIncrBy("user", "visits", 1)
+	expected := map[string]interface{}{"name": "alice", "visits": int64(1)}
+	if fields, _ := r.HGetAll("user"); !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected fields are %v, but found %v", expected, fields)
+	}
+
+	//teardown
+	r.Stop()
+}
+
+func TestHash_FieldTTL(t *testing.T) {
+	s := newStore(t)
+	s.HSet("session", "user", "alice")
+	s.HSetEx("session", "token", "secret", time.Millisecond*50)
+
+	ttl, err := s.HTTL("session", "token")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if ttl <= 0 || ttl > time.Millisecond*50 {
+		t.Errorf("Expected ttl is up to 50ms, but found %v", ttl)
+	}
+	if ttl, _ := s.HTTL("session", "user"); ttl != store.NoExpiration {
+		t.Errorf("Expected no expiration, but found %v", ttl)
+	}
+
+	time.Sleep(time.Millisecond * 60)
+
+	_, err = s.HGet("session", "token")
+	expected := "key 'token' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+	expectedFields := map[string]interface{}{"user": "alice"}
+	if fields, _ := s.HGetAll("session"); !reflect.DeepEqual(fields, expectedFields) {
+		t.Errorf("Expected fields are %v, but found %v", expectedFields, fields)
+	}
+	if n, _ := s.HLen("session"); n != 1 {
+		t.Errorf("Expected 1 field, but found %v", n)
+	}
+}
+
+func TestHash_FieldExpirePersist(t *testing.T) {
+	s := newStore(t)
+	s.HSet("session", "token", "secret")
+	s.HSet("session", "nonce", "123")
+
+	if err := s.HExpire("session", "token", time.Minute); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if err := s.HPersist("session", "token"); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if ttl, _ := s.HTTL("session", "token"); ttl != store.NoExpiration {
+		t.Errorf("Expected no expiration, but found %v", ttl)
+	}
+
+	// past time deletes the field
+	s.HExpireAt("session", "nonce", time.Now().Add(-time.Second))
+	if keys, _ := s.HKeys("session"); !reflect.DeepEqual(keys, []string{"token"}) {
+		t.Errorf("Expected keys are [token], but found %v", keys)
+	}
+
+	err := s.HExpire("session", "nonExisting", time.Minute)
+	expected := "key 'nonExisting' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestHash_AllFieldsExpired(t *testing.T) {
+	s := newStore(t)
+	s.HSetEx("session", "token", "secret", time.Millisecond*50)
+	s.HSetEx("session", "nonce", "123", time.Millisecond*50)
+	s.Set("otherKey", 1, store.NoExpiration)
+
+	time.Sleep(time.Millisecond * 60)
+
+	// gone for reads right away
+	if keys := s.Keys(); !reflect.DeepEqual(keys, []string{"otherKey"}) {
+		t.Errorf("Expected keys are [otherKey], but found %v", keys)
+	}
+	if _, err := s.Get("session"); err == nil {
+		t.Errorf("Expected the key to be expired")
+	}
+
+	// and removed by the background expiration
+	time.Sleep(time.Second * 2)
+	if stats := s.Stats(); stats.Items != 1 || stats.Expirations != 1 {
+		t.Errorf("Expected 1 item and 1 expiration, but found %+v", stats)
+	}
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog estimates number of unique elements with a fixed memory of 16384 6-bit registers packed
+// into 12KB, the standard error is about 0.81%
+
+const (
+	hllPrecision = 14
+	hllRegisters = 1 << hllPrecision
+	hllBits      = 6
+	hllMax       = 1<<hllBits - 1
+	hllSize      = hllRegisters * hllBits / 8
+)
+
+func init() {
+	gob.Register(&HyperLogLog{})
+	gob.Register(&hllAdd{})
+}
+
+type HyperLogLog struct {
+	Registers []byte
+}
+
+func newHyperLogLog() interface{} {
+	return &HyperLogLog{Registers: make([]byte, hllSize)}
+}
+
+// never empty, created by PFAdd even without elements and removed only by Delete as in redis
+func (h *HyperLogLog) len() int {
+	return 1
+}
+
+// raw registers
+func (h *HyperLogLog) clone() interface{} {
+	return append([]byte(nil), h.Registers...)
+}
+
+func (h *HyperLogLog) register(n int) uint8 {
+	pos, shift := n*hllBits/8, uint(n*hllBits%8)
+	v := uint16(h.Registers[pos])
+	if pos+1 < len(h.Registers) {
+		v |= uint16(h.Registers[pos+1]) << 8
+	}
+	return uint8(v>>shift) & hllMax
+}
+
+func (h *HyperLogLog) setRegister(n int, r uint8) {
+	pos, shift := n*hllBits/8, uint(n*hllBits%8)
+	v := uint16(h.Registers[pos])
+	if pos+1 < len(h.Registers) {
+		v |= uint16(h.Registers[pos+1]) << 8
+	}
+	v = v&^(hllMax<<shift) | uint16(r)<<shift
+	h.Registers[pos] = byte(v)
+	if pos+1 < len(h.Registers) {
+		h.Registers[pos+1] = byte(v >> 8)
+	}
+}
+
+// returns true if a register was changed
+func (h *HyperLogLog) add(element string) bool {
+	x := elementHash(element)
+	n := int(x & (hllRegisters - 1))
+	// position of the first set bit in the rest of the hash, the guard bit keeps it within 64-hllPrecision+1
+	r := uint8(bits.TrailingZeros64(x>>hllPrecision|1<<(64-hllPrecision)) + 1)
+	if r > h.register(n) {
+		h.setRegister(n, r)
+		return true
+	}
+	return false
+}
+
+// returns true if a register was changed
+func (h *HyperLogLog) merge(other *HyperLogLog) bool {
+	changed := false
+	for n := 0; n < hllRegisters; n++ {
+		if r := other.register(n); r > h.register(n) {
+			h.setRegister(n, r)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (h *HyperLogLog) count() uint64 {
+	sum, zeros := 0.0, 0
+	for n := 0; n < hllRegisters; n++ {
+		r := h.register(n)
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	m := float64(hllRegisters)
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros)) // linear counting is better for small numbers
+	}
+	return uint64(estimate + 0.5)
+}
+
+// fnv has a poor avalanche, so its result goes through the finalizer of murmur3
+func elementHash(element string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(element))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// returns true if the estimated number of elements was changed
+func (s *Store) PFAdd(key string, elements ...string) (bool, error) {
+	m := &hllAdd{Elements: elements}
+	err := s.mutate(key, newHyperLogLog, m)
+	return m.changed, err
+}
+
+type hllAdd struct {
+	Elements []string
+	changed  bool
+}
+
+func (m *hllAdd) apply(key string, val interface{}) (int64, error) {
+	h, ok := val.(*HyperLogLog)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	for _, e := range m.Elements {
+		m.changed = h.add(e) || m.changed
+	}
+	if !m.changed {
+		return 0, errUnchanged
+	}
+	return 0, nil
+}
+
+// estimated number of unique elements added to any of the keys
+func (s *Store) PFCount(keys ...string) (uint64, error) {
+	var result uint64
+	err := s.readMany(keys, func(vals []interface{}) error {
+		union, err := mergeHyperLogLogs(keys, vals)
+		if err == nil {
+			result = union.count()
+		}
+		return err
+	})
+	return result, err
+}
+
+// merges the keys into dest, the registers only grow, so the keys don't need to be locked
+// together with dest
+func (s *Store) PFMerge(dest string, keys ...string) error {
+	var union *HyperLogLog
+	err := s.readMany(keys, func(vals []interface{}) error {
+		var err error
+		union, err = mergeHyperLogLogs(keys, vals)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.change(dest, newHyperLogLog, func(val interface{}) (int64, error) {
+		h, ok := val.(*HyperLogLog)
+		if !ok {
+			return 0, fmt.Errorf(errWrongTypeFmt, dest)
+		}
+		h.merge(union)
+		return 0, nil
+	})
+}
+
+// missing keys are empty
+func mergeHyperLogLogs(keys []string, vals []interface{}) (*HyperLogLog, error) {
+	union := newHyperLogLog().(*HyperLogLog)
+	for n, val := range vals {
+		if val == nil {
+			continue
+		}
+		h, ok := val.(*HyperLogLog)
+		if !ok {
+			return nil, fmt.Errorf(errWrongTypeFmt, keys[n])
+		}
+		union.merge(h)
+	}
+	return union, nil
+}
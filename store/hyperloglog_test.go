@@ -0,0 +1,104 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPFAddPFCount(t *testing.T) {
+	s := newStore(t)
+
+	changed, err := s.PFAdd("visitors", "alice", "bob")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if !changed {
+		t.Errorf("Expected the estimate to be changed")
+	}
+	_, version, _ := s.GetWithVersion("visitors")
+	if changed, _ = s.PFAdd("visitors", "alice"); changed {
+		t.Errorf("Expected the estimate to stay the same")
+	}
+	if _, v, _ := s.GetWithVersion("visitors"); v != version {
+		t.Errorf("Expected version %v to stay the same, but found %v", version, v)
+	}
+	if n, _ := s.PFCount("visitors"); n != 2 {
+		t.Errorf("Expected count is 2, but found %v", n)
+	}
+	if n, _ := s.PFCount("nonExisting"); n != 0 {
+		t.Errorf("Expected count is 0, but found %v", n)
+	}
+
+	// registers take fixed memory
+	if val, _ := s.Get("visitors"); len(val.([]byte)) != 12288 {
+		t.Errorf("Expected 12288 bytes of registers, but found %v", len(val.([]byte)))
+	}
+}
+
+func TestPFCount_Accuracy(t *testing.T) {
+	s := newStore(t)
+	for _, total := range []int{1000, 100000} {
+		key := fmt.Sprintf("visitors%d", total)
+		batch := make([]string, 0, 1000)
+		for n := 0; n < total; n++ {
+			batch = append(batch, fmt.Sprintf("user%d", n))
+			if len(batch) == cap(batch) {
+				s.PFAdd(key, batch...)
+				batch = batch[:0]
+			}
+		}
+		n, _ := s.PFCount(key)
+		if e := math.Abs(float64(n)-float64(total)) / float64(total); e > 0.03 {
+			t.Errorf("Expected count is about %v, but found %v", total, n)
+		}
+	}
+}
+
+func TestPFMerge(t *testing.T) {
+	s := newStore(t)
+	for n := 0; n < 100; n++ {
+		s.PFAdd("monday", fmt.Sprintf("user%d", n))
+		s.PFAdd("tuesday", fmt.Sprintf("user%d", n+50))
+	}
+
+	if err := s.PFMerge("week", "monday", "tuesday"); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	week, _ := s.PFCount("week")
+	union, _ := s.PFCount("monday", "tuesday")
+	if week != union || week < 145 || week > 155 {
+		t.Errorf("Expected count is about 150 for both, but found %v and %v", week, union)
+	}
+
+	s.Set("someKey", 123, time.Minute)
+	err := s.PFMerge("week", "monday", "someKey")
+	expected := "wrong type for key 'someKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestHyperLogLog_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.PFAdd("visitors", "alice", "bob")
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.PFAdd("visitors", "carol")
+	if n, _ := r.PFCount("visitors"); n != 3 {
+		t.Errorf("Expected count is 3, but found %v", n)
+	}
+
+	//teardown
+	r.Stop()
+}
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+)
+
+// List is a native list value, created by push operations and removed once it is empty,
+// indexes of range operations are inclusive and negative ones count from the end as in redis
+
+func init() {
+	gob.Register(&List{})
+	gob.Register(&listPush{})
+	gob.Register(&listPop{})
+	gob.Register(&listTrim{})
+}
+
+type List struct {
+	Items []interface{}
+}
+
+func (l *List) len() int {
+	return len(l.Items)
+}
+
+func (l *List) clone() interface{} {
+	return append([]interface{}(nil), l.Items...)
+}
+
+func (l *List) bounds(start, stop int) (int, int) {
+	return bounds(len(l.Items), start, stop)
+}
+
+// turns redis like inclusive indexes into bounds of a slice of n, start >= stop for an empty range
+func bounds(n, start, stop int) (int, int) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	stop++
+	if stop > n {
+		stop = n
+	}
+	if stop < 0 { // negative stop beyond the start of the slice
+		stop = 0
+	}
+	if start > stop {
+		start = stop
+	}
+	return start, stop
+}
+
+func newList() interface{} {
+	return &List{}
+}
+
+// returns length of the list after the push, the first value ends up the last at the head
+func (s *Store) LPush(key string, values ...interface{}) (int, error) {
+	return s.push(key, values, true)
+}
+
+// returns length of the list after the push
+func (s *Store) RPush(key string, values ...interface{}) (int, error) {
+	return s.push(key, values, false)
+}
+
+func (s *Store) push(key string, values []interface{}, head bool) (int, error) {
+	m := &listPush{Values: values, Head: head}
+	err := s.mutate(key, newList, m)
+	return m.n, err
+}
+
+type listPush struct {
+	Values []interface{}
+	Head   bool
+	n      int // length after the push
+}
+
+func (m *listPush) apply(key string, val interface{}) (int64, error) {
+	l, ok := val.(*List)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	if m.Head {
+		items := make([]interface{}, 0, len(l.Items)+len(m.Values))
+		for i := len(m.Values) - 1; i >= 0; i-- {
+			items = append(items, m.Values[i])
+		}
+		l.Items = append(items, l.Items...)
+	} else {
+		l.Items = append(l.Items, m.Values...)
+	}
+	m.n = len(l.Items)
+	return sizeOfValues(m.Values), nil
+}
+
+func (s *Store) LPop(key string) (interface{}, error) {
+	return s.pop(key, true)
+}
+
+func (s *Store) RPop(key string) (interface{}, error) {
+	return s.pop(key, false)
+}
+
+func (s *Store) pop(key string, head bool) (interface{}, error) {
+	m := &listPop{Head: head}
+	err := s.mutate(key, nil, m)
+	return m.popped, err
+}
+
+type listPop struct {
+	Head   bool
+	popped interface{}
+}
+
+func (m *listPop) apply(key string, val interface{}) (int64, error) {
+	l, ok := val.(*List)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	if len(l.Items) == 0 {
+		return 0, fmt.Errorf(errKeyNotFoundFmt, key)
+	}
+	if m.Head {
+		m.popped = l.Items[0]
+		l.Items[0] = nil
+		l.Items = l.Items[1:]
+	} else {
+		m.popped = l.Items[len(l.Items)-1]
+		l.Items[len(l.Items)-1] = nil
+		l.Items = l.Items[:len(l.Items)-1]
+	}
+	return -sizeOfValue(reflect.ValueOf(m.popped)), nil
+}
+
+// missing key is an empty list
+func (s *Store) LRange(key string, start, stop int) ([]interface{}, error) {
+	result := make([]interface{}, 0)
+	err := s.read(key, func(val interface{}) error {
+		l, err := asList(key, val)
+		if err == nil {
+			start, stop := l.bounds(start, stop)
+			result = append(result, l.Items[start:stop]...)
+		}
+		return err
+	})
+	return result, err
+}
+
+func (s *Store) LLen(key string) (int, error) {
+	n := 0
+	err := s.read(key, func(val interface{}) error {
+		l, err := asList(key, val)
+		if err == nil {
+			n = len(l.Items)
+		}
+		return err
+	})
+	return n, err
+}
+
+// keeps only the given range of the list
+func (s *Store) LTrim(key string, start, stop int) error {
+	return s.mutate(key, nil, &listTrim{Start: start, Stop: stop})
+}
+
+type listTrim struct {
+	Start, Stop int
+}
+
+func (m *listTrim) apply(key string, val interface{}) (int64, error) {
+	l, ok := val.(*List)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	start, stop := l.bounds(m.Start, m.Stop)
+	removed := sizeOfValues(l.Items[:start]) + sizeOfValues(l.Items[stop:])
+	l.Items = append([]interface{}(nil), l.Items[start:stop]...)
+	return -removed, nil
+}
+
+// missing key is an empty list
+func asList(key string, val interface{}) (*List, error) {
+	if val == nil {
+		return &List{}, nil
+	}
+	l, ok := val.(*List)
+	if !ok {
+		return nil, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	return l, nil
+}
+
+func sizeOfValues(values []interface{}) int64 {
+	return sizeOfValue(reflect.ValueOf(values))
+}
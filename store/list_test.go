@@ -0,0 +1,142 @@
+package store_test
+
+import (
+	"github.com/baratov/golang-playground/store"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPushPop(t *testing.T) {
+	s := newStore(t)
+
+	n, err := s.RPush("someList", "b", "c")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != 2 {
+		t.Errorf("Expected length is 2, but found %v", n)
+	}
+	n, _ = s.LPush("someList", "a", "z")
+	if n != 4 {
+		t.Errorf("Expected length is 4, but found %v", n)
+	}
+
+	expected := []interface{}{"z", "a", "b", "c"}
+	if val, _ := s.Get("someList"); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected value is %v, but found %v", expected, val)
+	}
+
+	for _, pop := range []struct {
+		fn       func(string) (interface{}, error)
+		expected interface{}
+	}{{s.LPop, "z"}, {s.RPop, "c"}, {s.RPop, "b"}, {s.LPop, "a"}} {
+		val, err := pop.fn("someList")
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if val != pop.expected {
+			t.Errorf("Expected value is %v, but found %v", pop.expected, val)
+		}
+	}
+
+	// empty list is removed
+	_, err = s.LPop("someList")
+	expectedErr := "key 'someList' not found"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error is %s, but found %v", expectedErr, err)
+	}
+}
+
+func TestLRange(t *testing.T) {
+	s := newStore(t)
+	s.RPush("someList", 0, 1, 2, 3, 4)
+
+	for _, c := range []struct {
+		start, stop int
+		expected    []interface{}
+	}{
+		{0, -1, []interface{}{0, 1, 2, 3, 4}},
+		{1, 2, []interface{}{1, 2}},
+		{-2, -1, []interface{}{3, 4}},
+		{-100, 100, []interface{}{0, 1, 2, 3, 4}},
+		{3, 1, []interface{}{}},
+		{5, 10, []interface{}{}},
+		{0, -100, []interface{}{}},
+		{-100, -100, []interface{}{}},
+	} {
+		val, err := s.LRange("someList", c.start, c.stop)
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if !reflect.DeepEqual(val, c.expected) {
+			t.Errorf("Expected range [%v, %v] is %v, but found %v", c.start, c.stop, c.expected, val)
+		}
+	}
+
+	if val, _ := s.LRange("nonExisting", 0, -1); len(val) != 0 {
+		t.Errorf("Expected empty range, but found %v", val)
+	}
+}
+
+func TestLTrim(t *testing.T) {
+	s := newStore(t)
+	s.RPush("someList", 0, 1, 2, 3, 4)
+
+	if err := s.LTrim("someList", 1, -2); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	expected := []interface{}{1, 2, 3}
+	if val, _ := s.LRange("someList", 0, -1); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected value is %v, but found %v", expected, val)
+	}
+	if n, _ := s.LLen("someList"); n != 3 {
+		t.Errorf("Expected length is 3, but found %v", n)
+	}
+
+	// an empty range removes the list
+	if err := s.LTrim("someList", 0, -100); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if _, err := s.Get("someList"); err == nil {
+		t.Errorf("Expected list to be removed")
+	}
+}
+
+func TestList_WrongType(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Minute)
+
+	_, err := s.RPush("someKey", 1)
+	expected := "wrong type for key 'someKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+	_, err = s.LLen("someKey")
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestList_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.RPush("someList", "a", "b", "c")
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.RPop("someList")
+	expected := []interface{}{"a", "b"}
+	if val, _ := r.LRange("someList", 0, -1); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected value is %v, but found %v", expected, val)
+	}
+
+	//teardown
+	r.Stop()
+}
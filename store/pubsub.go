@@ -0,0 +1,221 @@
+package store
+
+import (
+	"errors"
+	"sync"
+)
+
+// messages published to a channel go to subscriptions of the channel and of glob patterns which
+// match it, they are not stored: subscriptions made later don't get them, like watchers
+// a subscription which doesn't keep up is closed with ErrSubscriptionOverflow
+
+const DefSubscriptionBuffer = 1024
+
+var ErrSubscriptionOverflow = errors.New("subscription is too slow, messages were dropped")
+
+type Message struct {
+	Channel string      `json:"channel"`
+	Pattern string      `json:"pattern,omitempty"` // which matched the channel, "" for a subscription to the channel
+	Payload interface{} `json:"payload"`
+}
+
+type Subscription struct {
+	C        <-chan Message // closed by Close, Stop of the store or overflow
+	c        chan Message
+	ps       *pubsub
+	channels map[string]bool
+	patterns map[string]bool
+	closed   bool
+	err      error
+}
+
+// subscriptions by channel and by pattern, all of them including those to nothing for Stop
+type pubsub struct {
+	mu       sync.Mutex
+	channels subscribers
+	patterns subscribers
+	all      map[*Subscription]bool
+	stopped  bool
+}
+
+type subscribers map[string]map[*Subscription]bool
+
+// a subscription to nothing yet, buffer 0 takes DefSubscriptionBuffer, it is closed already
+// once the store is stopped
+func (s *Store) NewSubscription(buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefSubscriptionBuffer
+	}
+	c := make(chan Message, buffer)
+	sub := &Subscription{
+		C:        c,
+		c:        c,
+		ps:       &s.pubsub,
+		channels: make(map[string]bool),
+		patterns: make(map[string]bool),
+	}
+
+	ps := &s.pubsub
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.stopped {
+		sub.closed = true
+		close(sub.c)
+		return sub
+	}
+	if ps.all == nil {
+		ps.all = make(map[*Subscription]bool)
+	}
+	ps.all[sub] = true
+	return sub
+}
+
+// returns number of subscriptions which got the message, a subscription gets it once for the channel
+// and once for every pattern which matches it
+func (s *Store) Publish(channel string, payload interface{}) int {
+	ps := &s.pubsub
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	received := 0
+	send := func(sub *Subscription, pattern string) {
+		select {
+		case sub.c <- Message{Channel: channel, Pattern: pattern, Payload: payload}:
+			received++
+		default:
+			ps.remove(sub, ErrSubscriptionOverflow)
+		}
+	}
+	for sub := range ps.channels[channel] {
+		send(sub, "")
+	}
+	for pattern, subs := range ps.patterns {
+		if !Match(pattern, channel) {
+			continue
+		}
+		for sub := range subs {
+			send(sub, pattern)
+		}
+	}
+	return received
+}
+
+// returns number of channels and patterns the subscription has
+func (sub *Subscription) Subscribe(channels ...string) int {
+	return sub.change(func() {
+		for _, ch := range channels {
+			sub.channels[ch] = true
+			sub.ps.channels.add(ch, sub)
+		}
+	})
+}
+
+// returns number of channels and patterns the subscription has
+func (sub *Subscription) PSubscribe(patterns ...string) int {
+	return sub.change(func() {
+		for _, p := range patterns {
+			sub.patterns[p] = true
+			sub.ps.patterns.add(p, sub)
+		}
+	})
+}
+
+// no channels unsubscribes from all of them, returns number of channels and patterns left
+func (sub *Subscription) Unsubscribe(channels ...string) int {
+	return sub.change(func() {
+		if len(channels) == 0 {
+			channels = keysOf(sub.channels)
+		}
+		for _, ch := range channels {
+			delete(sub.channels, ch)
+			sub.ps.channels.remove(ch, sub)
+		}
+	})
+}
+
+// no patterns unsubscribes from all of them, returns number of channels and patterns left
+func (sub *Subscription) PUnsubscribe(patterns ...string) int {
+	return sub.change(func() {
+		if len(patterns) == 0 {
+			patterns = keysOf(sub.patterns)
+		}
+		for _, p := range patterns {
+			delete(sub.patterns, p)
+			sub.ps.patterns.remove(p, sub)
+		}
+	})
+}
+
+func (sub *Subscription) change(fn func()) int {
+	sub.ps.mu.Lock()
+	defer sub.ps.mu.Unlock()
+	if !sub.closed {
+		fn()
+	}
+	return len(sub.channels) + len(sub.patterns)
+}
+
+// stops the subscription, C is closed once the messages in its buffer are read
+func (sub *Subscription) Close() {
+	sub.ps.mu.Lock()
+	sub.ps.remove(sub, nil)
+	sub.ps.mu.Unlock()
+}
+
+// ErrSubscriptionOverflow once the subscription was closed because of its full buffer
+func (sub *Subscription) Err() error {
+	sub.ps.mu.Lock()
+	defer sub.ps.mu.Unlock()
+	return sub.err
+}
+
+// should be called under lock
+func (ps *pubsub) remove(sub *Subscription, err error) {
+	if sub.closed {
+		return
+	}
+	for ch := range sub.channels {
+		ps.channels.remove(ch, sub)
+	}
+	for p := range sub.patterns {
+		ps.patterns.remove(p, sub)
+	}
+	delete(ps.all, sub)
+	sub.channels, sub.patterns = map[string]bool{}, map[string]bool{}
+	sub.closed, sub.err = true, err
+	close(sub.c)
+}
+
+func (ps *pubsub) closeAll() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.stopped = true
+	for sub := range ps.all {
+		ps.remove(sub, nil)
+	}
+}
+
+func (m *subscribers) add(name string, sub *Subscription) {
+	if *m == nil {
+		*m = make(subscribers)
+	}
+	if (*m)[name] == nil {
+		(*m)[name] = make(map[*Subscription]bool)
+	}
+	(*m)[name][sub] = true
+}
+
+func (m subscribers) remove(name string, sub *Subscription) {
+	delete(m[name], sub)
+	if len(m[name]) == 0 {
+		delete(m, name)
+	}
+}
+
+func keysOf(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
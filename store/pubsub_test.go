@@ -0,0 +1,98 @@
+package store_test
+
+import (
+	"github.com/baratov/golang-playground/store"
+	"testing"
+	"time"
+)
+
+func TestPublish(t *testing.T) {
+	s := newStore(t)
+	sub := s.NewSubscription(0)
+	defer sub.Close()
+	if n := sub.Subscribe("news", "sport"); n != 2 {
+		t.Errorf("Expected 2 subscriptions, but found %v", n)
+	}
+	if n := sub.PSubscribe("news.*"); n != 3 {
+		t.Errorf("Expected 3 subscriptions, but found %v", n)
+	}
+
+	if n := s.Publish("news.tech", "gopher"); n != 1 {
+		t.Errorf("Expected 1 receiver, but found %v", n)
+	}
+	if n := s.Publish("news", 42); n != 1 {
+		t.Errorf("Expected 1 receiver, but found %v", n)
+	}
+	if n := s.Publish("weather", "rain"); n != 0 {
+		t.Errorf("Expected no receivers, but found %v", n)
+	}
+
+	expected := []store.Message{
+		{Channel: "news.tech", Pattern: "news.*", Payload: "gopher"},
+		{Channel: "news", Payload: 42},
+	}
+	for _, m := range expected {
+		if found := <-sub.C; found != m {
+			t.Errorf("Expected message is %v, but found %v", m, found)
+		}
+	}
+
+	if n := sub.Unsubscribe(); n != 1 {
+		t.Errorf("Expected 1 subscription left, but found %v", n)
+	}
+	if n := sub.PUnsubscribe("news.*"); n != 0 {
+		t.Errorf("Expected no subscriptions left, but found %v", n)
+	}
+	if n := s.Publish("news", "nobody"); n != 0 {
+		t.Errorf("Expected no receivers, but found %v", n)
+	}
+}
+
+func TestPublish_Overflow(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+	)
+	slow := s.NewSubscription(2)
+	slow.Subscribe("news")
+	fast := s.NewSubscription(0)
+	fast.Subscribe("news")
+	defer fast.Close()
+	idle := s.NewSubscription(0)
+
+	for n := 0; n < 3; n++ {
+		s.Publish("news", n)
+	}
+	count := 0
+	for range slow.C {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 messages before overflow, but found %v", count)
+	}
+	if err := slow.Err(); err != store.ErrSubscriptionOverflow {
+		t.Errorf("Expected %v, but found %v", store.ErrSubscriptionOverflow, err)
+	}
+	if len(fast.C) != 3 {
+		t.Errorf("Expected 3 messages of the fast subscription, but found %v", len(fast.C))
+	}
+
+	s.Stop()
+	if n := fast.Subscribe("sport"); n != 0 {
+		t.Errorf("Expected no subscriptions of a stopped store, but found %v", n)
+	}
+	closed := func(c <-chan store.Message) bool {
+		select {
+		case _, ok := <-c:
+			return !ok
+		case <-time.After(time.Second):
+			return false
+		}
+	}
+	// a subscription to nothing is closed by Stop as well
+	if !closed(idle.C) {
+		t.Errorf("Expected C of the idle subscription to be closed")
+	}
+	if !closed(s.NewSubscription(0).C) {
+		t.Errorf("Expected C of a subscription of a stopped store to be closed")
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"sort"
+	"strings"
+)
+
+// keys are iterated in order, so a cursor is just the last key seen, it stays valid whatever
+// is added or deleted, patterns are globs as in redis: * ? [abc] [^a-z] and \ for escape
+
+const DefScanCount = 10
+
+// returns keys after the cursor which match the pattern and the cursor of the next call, it is ""
+// once the scan is over, every key which exists during the whole scan is returned once,
+// count keys are checked per call, so a page may have fewer keys or none before the end
+func (s *Store) Scan(cursor, match string, count int) ([]string, string) {
+	if count <= 0 {
+		count = DefScanCount
+	}
+	prefix := literalPrefix(match)
+
+	var checked []string
+	more := false
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		x := sh.ordered.seek(0, cursor, false)
+		if cursor < prefix {
+			x = sh.ordered.seek(0, prefix, true)
+		}
+		for n := 0; x != nil && strings.HasPrefix(x.member, prefix); x = x.levels[0].next {
+			if n == count {
+				more = true // the rest of the shard goes after the keys taken from it
+				break
+			}
+			if !sh.items[x.member].isExpired() {
+				checked = append(checked, x.member)
+				n++
+			}
+		}
+		sh.mu.RUnlock()
+	}
+
+	sort.Strings(checked)
+	if len(checked) > count {
+		checked, more = checked[:count], true
+	}
+	next := ""
+	if more {
+		next = checked[len(checked)-1]
+	}
+
+	keys := make([]string, 0, len(checked))
+	for _, key := range checked {
+		if match == "" || Match(match, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, next
+}
+
+// all live keys which match the pattern
+func (s *Store) KeysMatching(pattern string) []string {
+	keys := make([]string, 0)
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		for key, item := range sh.items {
+			if !item.isExpired() && Match(pattern, key) {
+				keys = append(keys, key)
+			}
+		}
+		sh.mu.RUnlock()
+	}
+	return keys
+}
+
+// true if the whole key matches the glob pattern, an unclosed [ takes the rest of the pattern
+func Match(pattern, key string) bool {
+	p, k := 0, 0
+	star, starKey := -1, 0 // the last * and the position in the key it was tried from
+	for k < len(key) {
+		if p < len(pattern) {
+			switch c := pattern[p]; c {
+			case '*':
+				star, starKey = p, k
+				p++
+				continue
+			case '?':
+				p++
+				k++
+				continue
+			case '[':
+				if end, ok := matchClass(pattern, p+1, key[k]); ok {
+					p, k = end, k+1
+					continue
+				}
+			default:
+				width := 1
+				if c == '\\' && p+1 < len(pattern) {
+					c, width = pattern[p+1], 2
+				}
+				if c == key[k] {
+					p, k = p+width, k+1
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		starKey++ // * takes one more byte
+		p, k = star+1, starKey
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matches the class which starts at p right after [, returns position after its ]
+func matchClass(pattern string, p int, c byte) (int, bool) {
+	negate := p < len(pattern) && pattern[p] == '^'
+	if negate {
+		p++
+	}
+	matched := false
+	for ; p < len(pattern) && pattern[p] != ']'; p++ {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			matched = matched || pattern[p] == c
+		case p+2 < len(pattern) && pattern[p+1] == '-' && pattern[p+2] != ']':
+			from, to := pattern[p], pattern[p+2]
+			if from > to {
+				from, to = to, from
+			}
+			matched = matched || from <= c && c <= to
+			p += 2
+		default:
+			matched = matched || pattern[p] == c
+		}
+	}
+	if p < len(pattern) {
+		p++ // ]
+	}
+	return p, matched != negate
+}
+
+// part of the pattern before the first special character, every matching key starts with it
+func literalPrefix(pattern string) string {
+	if n := strings.IndexAny(pattern, `*?[\`); n >= 0 {
+		return pattern[:n]
+	}
+	return pattern
+}
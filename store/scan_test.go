@@ -0,0 +1,137 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, key string
+		expected     bool
+	}{
+		{"", "", true},
+		{"*", "anything", true},
+		{"user:*", "user:42", true},
+		{"user:*", "users:42", false},
+		{"*:42", "user:42", true},
+		{"u?er", "user", true},
+		{"u?er", "uer", false},
+		{"h[ae]llo", "hello", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[c-a]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"*a*a*a*b", "aaaaaaaaaaaaaaaaaaaaaaaaaaaa", false},
+		{"user:[12", "user:2", true},
+	}
+	for _, c := range cases {
+		if found := store.Match(c.pattern, c.key); found != c.expected {
+			t.Errorf("Expected match of %v and %v is %v, but found %v", c.pattern, c.key, c.expected, found)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	s := newStore(t)
+	var expected []string
+	for n := 0; n < 1000; n++ {
+		key := fmt.Sprintf("key%04d", n)
+		s.Set(key, n, time.Minute)
+		expected = append(expected, key)
+	}
+
+	var found []string
+	cursor, calls := "", 0
+	for {
+		var keys []string
+		keys, cursor = s.Scan(cursor, "", 100)
+		found = append(found, keys...)
+		calls++
+		if cursor == "" {
+			break
+		}
+	}
+	if fmt.Sprint(found) != fmt.Sprint(expected) {
+		t.Errorf("Expected all 1000 keys in order, but found %v", len(found))
+	}
+	if calls > 11 {
+		t.Errorf("Expected at most 11 calls, but found %v", calls)
+	}
+}
+
+func TestScan_Match(t *testing.T) {
+	s := newStore(t)
+	for n := 0; n < 100; n++ {
+		s.Set(fmt.Sprintf("user:%d", n), n, time.Minute)
+		s.Set(fmt.Sprintf("order:%d", n), n, time.Minute)
+	}
+	s.Set("user:expired", 0, time.Millisecond)
+	time.Sleep(time.Millisecond * 2)
+
+	var found []string
+	cursor := ""
+	for {
+		var keys []string
+		keys, cursor = s.Scan(cursor, "user:*5", 10)
+		found = append(found, keys...)
+		if cursor == "" {
+			break
+		}
+	}
+	expected := "[user:15 user:25 user:35 user:45 user:5 user:55 user:65 user:75 user:85 user:95]"
+	if fmt.Sprint(found) != expected {
+		t.Errorf("Expected keys are %v, but found %v", expected, found)
+	}
+
+	keys := s.KeysMatching("order:?")
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[order:0 order:1 order:2 order:3 order:4 order:5 order:6 order:7 order:8 order:9]" {
+		t.Errorf("Expected order:0 to order:9, but found %v", keys)
+	}
+}
+
+// keys which exist during the whole scan are returned once whatever is added or deleted meanwhile
+func TestScan_Changes(t *testing.T) {
+	s := newStore(t)
+	for n := 0; n < 500; n++ {
+		s.Set(fmt.Sprintf("stable%03d", n), n, time.Minute)
+		s.Set(fmt.Sprintf("deleted%03d", n), n, time.Minute)
+	}
+
+	seen := make(map[string]int)
+	cursor, n := "", 0
+	for {
+		var keys []string
+		keys, cursor = s.Scan(cursor, "", 50)
+		for _, key := range keys {
+			seen[key]++
+		}
+		s.Delete(fmt.Sprintf("deleted%03d", n))
+		s.Set(fmt.Sprintf("added%03d", n), n, time.Minute)
+		n++
+		if cursor == "" {
+			break
+		}
+	}
+
+	for n := 0; n < 500; n++ {
+		if key := fmt.Sprintf("stable%03d", n); seen[key] != 1 {
+			t.Errorf("Expected %v to be returned once, but found %v times", key, seen[key])
+		}
+	}
+	for key, times := range seen {
+		if times > 1 {
+			t.Errorf("Expected %v to be returned once, but found %v times", key, times)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"sort"
+)
+
+// Set is a native set of strings, created by SAdd and removed once it is empty
+
+func init() {
+	gob.Register(&Set{})
+	gob.Register(&setAdd{})
+	gob.Register(&setRem{})
+}
+
+type Set struct {
+	Members map[string]bool
+}
+
+func (set *Set) len() int {
+	return len(set.Members)
+}
+
+// sorted members
+func (set *Set) clone() interface{} {
+	return set.sorted()
+}
+
+func (set *Set) sorted() []string {
+	members := make([]string, 0, len(set.Members))
+	for m := range set.Members {
+		members = append(members, m)
+	}
+	sort.Strings(members)
+	return members
+}
+
+func newSet() interface{} {
+	return &Set{Members: make(map[string]bool)}
+}
+
+// returns number of members which were not in the set before
+func (s *Store) SAdd(key string, members ...string) (int, error) {
+	m := &setAdd{Members: members}
+	err := s.mutate(key, newSet, m)
+	return m.added, err
+}
+
+type setAdd struct {
+	Members []string
+	added   int
+}
+
+func (m *setAdd) apply(key string, val interface{}) (int64, error) {
+	set, ok := val.(*Set)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	var delta int64
+	for _, member := range m.Members {
+		if !set.Members[member] {
+			set.Members[member] = true
+			delta += sizeOfMember(member)
+			m.added++
+		}
+	}
+	if m.added == 0 {
+		return 0, errUnchanged
+	}
+	return delta, nil
+}
+
+// returns number of members which were removed
+func (s *Store) SRem(key string, members ...string) (int, error) {
+	m := &setRem{Members: members}
+	err := s.mutate(key, nil, m)
+	return m.removed, err
+}
+
+type setRem struct {
+	Members []string
+	removed int
+}
+
+func (m *setRem) apply(key string, val interface{}) (int64, error) {
+	set, ok := val.(*Set)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	var delta int64
+	for _, member := range m.Members {
+		if set.Members[member] {
+			delete(set.Members, member)
+			delta -= sizeOfMember(member)
+			m.removed++
+		}
+	}
+	if m.removed == 0 {
+		return 0, errUnchanged
+	}
+	return delta, nil
+}
+
+func (s *Store) SIsMember(key, member string) (bool, error) {
+	found := false
+	err := s.read(key, func(val interface{}) error {
+		set, err := asSet(key, val)
+		if err == nil {
+			found = set.Members[member]
+		}
+		return err
+	})
+	return found, err
+}
+
+// members in sorted order, missing key is an empty set
+func (s *Store) SMembers(key string) ([]string, error) {
+	var members []string
+	err := s.read(key, func(val interface{}) error {
+		set, err := asSet(key, val)
+		if err == nil {
+			members = set.sorted()
+		}
+		return err
+	})
+	return members, err
+}
+
+func (s *Store) SCard(key string) (int, error) {
+	n := 0
+	err := s.read(key, func(val interface{}) error {
+		set, err := asSet(key, val)
+		if err == nil {
+			n = len(set.Members)
+		}
+		return err
+	})
+	return n, err
+}
+
+// members of any of the sets
+func (s *Store) SUnion(keys ...string) ([]string, error) {
+	return s.setAlgebra(keys, func(sets []*Set) *Set {
+		result := newSet().(*Set)
+		for _, set := range sets {
+			for m := range set.Members {
+				result.Members[m] = true
+			}
+		}
+		return result
+	})
+}
+
+// members of all the sets
+func (s *Store) SInter(keys ...string) ([]string, error) {
+	return s.setAlgebra(keys, func(sets []*Set) *Set {
+		result := newSet().(*Set)
+		if len(sets) == 0 {
+			return result
+		}
+	members:
+		for m := range sets[0].Members {
+			for _, set := range sets[1:] {
+				if !set.Members[m] {
+					continue members
+				}
+			}
+			result.Members[m] = true
+		}
+		return result
+	})
+}
+
+// members of the first set which are not in any of the rest
+func (s *Store) SDiff(keys ...string) ([]string, error) {
+	return s.setAlgebra(keys, func(sets []*Set) *Set {
+		result := newSet().(*Set)
+		if len(sets) == 0 {
+			return result
+		}
+	members:
+		for m := range sets[0].Members {
+			for _, set := range sets[1:] {
+				if set.Members[m] {
+					continue members
+				}
+			}
+			result.Members[m] = true
+		}
+		return result
+	})
+}
+
+func (s *Store) setAlgebra(keys []string, fn func(sets []*Set) *Set) ([]string, error) {
+	var members []string
+	err := s.readMany(keys, func(vals []interface{}) error {
+		sets := make([]*Set, len(vals))
+		for n, val := range vals {
+			set, err := asSet(keys[n], val)
+			if err != nil {
+				return err
+			}
+			sets[n] = set
+		}
+		members = fn(sets).sorted()
+		return nil
+	})
+	return members, err
+}
+
+// missing key is an empty set
+func asSet(key string, val interface{}) (*Set, error) {
+	if val == nil {
+		return newSet().(*Set), nil
+	}
+	set, ok := val.(*Set)
+	if !ok {
+		return nil, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	return set, nil
+}
+
+func sizeOfMember(m string) int64 {
+	return int64(len(m)) + 1
+}
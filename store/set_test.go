@@ -0,0 +1,94 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSAddSRem(t *testing.T) {
+	s := newStore(t)
+
+	n, err := s.SAdd("tags", "go", "redis", "go")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 added members, but found %v", n)
+	}
+	if n, _ = s.SAdd("tags", "redis", "gob"); n != 1 {
+		t.Errorf("Expected 1 added member, but found %v", n)
+	}
+	_, version, _ := s.GetWithVersion("tags")
+	s.SAdd("tags", "go")
+	s.SRem("tags", "rust")
+	if _, v, _ := s.GetWithVersion("tags"); v != version {
+		t.Errorf("Expected version %v to stay the same, but found %v", version, v)
+	}
+
+	if found, _ := s.SIsMember("tags", "gob"); !found {
+		t.Errorf("Expected gob to be a member")
+	}
+	if n, _ := s.SCard("tags"); n != 3 {
+		t.Errorf("Expected 3 members, but found %v", n)
+	}
+
+	if n, _ = s.SRem("tags", "gob", "rust"); n != 1 {
+		t.Errorf("Expected 1 removed member, but found %v", n)
+	}
+	expected := []string{"go", "redis"}
+	if members, _ := s.SMembers("tags"); !reflect.DeepEqual(members, expected) {
+		t.Errorf("Expected members are %v, but found %v", expected, members)
+	}
+	if val, _ := s.Get("tags"); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected value is %v, but found %v", expected, val)
+	}
+
+	// empty set is removed
+	s.SRem("tags", "go", "redis")
+	if keys := s.Keys(); len(keys) != 0 {
+		t.Errorf("Expected no keys, but found %v", keys)
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	s := newStore(t)
+	s.SAdd("a", "1", "2", "3")
+	s.SAdd("b", "2", "3", "4")
+	s.SAdd("c", "3", "5")
+
+	for _, c := range []struct {
+		name     string
+		fn       func(...string) ([]string, error)
+		expected []string
+	}{
+		{"union", s.SUnion, []string{"1", "2", "3", "4", "5"}},
+		{"inter", s.SInter, []string{"3"}},
+		{"diff", s.SDiff, []string{"1"}},
+	} {
+		members, err := c.fn("a", "b", "c")
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if !reflect.DeepEqual(members, c.expected) {
+			t.Errorf("Expected %s is %v, but found %v", c.name, c.expected, members)
+		}
+	}
+
+	// missing key is an empty set
+	if members, _ := s.SInter("a", "nonExisting"); len(members) != 0 {
+		t.Errorf("Expected empty intersection, but found %v", members)
+	}
+}
+
+func TestSet_WrongType(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Minute)
+	s.SAdd("tags", "go")
+
+	_, err := s.SUnion("tags", "someKey")
+	expected := "wrong type for key 'someKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
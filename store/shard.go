@@ -0,0 +1,227 @@
+package store
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// part of the keyspace with its own lock, expiration heap and size accounting
+
+type shard struct {
+	mu       sync.RWMutex     // https://github.com/golang/go/wiki/MutexOrChannel
+	items    map[string]*item // sync.Map could give synchronization out of the box and help to avoid cache contention
+	expiring expirationHeap
+	fields   fieldHeap                              // expiring fields of hashes
+	tracked  map[string]map[string]*fieldExpiration // entries of fields by key and field
+	ordered  *skipList                              // keys in order for Scan, all scores are 0
+	usage    *usage
+	watchers *watchers
+	history  *history // nil keeps no history
+	version  uint64   // the last version given to an item of the shard
+}
+
+// totals over all shards, updated atomically
+type usage struct {
+	items       int64
+	bytes       int64
+	evictions   uint64
+	expirations uint64
+}
+
+func (u *usage) count() int64 {
+	return atomic.LoadInt64(&u.items)
+}
+
+func (u *usage) size() int64 {
+	return atomic.LoadInt64(&u.bytes)
+}
+
+func newShard(u *usage, ws *watchers) *shard {
+	return &shard{
+		items:    make(map[string]*item),
+		tracked:  make(map[string]map[string]*fieldExpiration),
+		ordered:  newSkipList(),
+		usage:    u,
+		watchers: ws,
+	}
+}
+
+func (sh *shard) get(key string) (interface{}, error) {
+	i, err := sh.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	i.touch()
+	return i.value(), nil
+}
+
+// returns live item without touching it
+func (sh *shard) lookup(key string) (*item, error) {
+	i, ok := sh.items[key]
+	if ok && !i.isExpired() {
+		return i, nil
+	}
+	return nil, fmt.Errorf(errKeyNotFoundFmt, key)
+}
+
+// runs fn under write lock of the shard, the lock is released even if fn panics
+func (sh *shard) locked(fn func() error) error {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return fn()
+}
+
+// new items get the next version, restored ones keep their own
+func (sh *shard) set(key string, i *item) {
+	old := sh.detach(key)
+
+	if i.Version == 0 {
+		i.Version = sh.nextVersion()
+	} else if i.Version > sh.version {
+		sh.version = i.Version
+	}
+	i.key = key
+	i.size = sizeOf(key, i.Value)
+	i.touch()
+	i.index = -1
+	sh.items[key] = i
+	sh.ordered.insert(key, 0)
+	if !i.Expiration.IsZero() {
+		heap.Push(&sh.expiring, i)
+	}
+	sh.trackFields(i, true)
+	atomic.AddInt64(&sh.usage.items, 1)
+	atomic.AddInt64(&sh.usage.bytes, i.size)
+
+	switch {
+	case old == nil:
+		sh.emit(EventSet, key, 0, i, nil)
+	case old.isExpired(): // was not removed by expire yet
+		sh.emit(EventExpire, key, old.Version, nil, nil)
+		sh.emit(EventSet, key, 0, i, nil)
+	default:
+		sh.emit(EventUpdate, key, old.Version, i, nil)
+	}
+}
+
+func (sh *shard) nextVersion() uint64 {
+	sh.version++
+	return sh.version
+}
+
+// should be called under write lock after the value of the item was changed in place by m,
+// nil m tells watchers the whole value
+func (sh *shard) changed(i *item, size int64, m mutation) {
+	atomic.AddInt64(&sh.usage.bytes, size-i.size)
+	i.size = size
+	old := i.Version
+	i.Version = sh.nextVersion()
+	i.touch()
+	sh.trackFields(i, false)
+	sh.emit(EventUpdate, i.key, old, i, m)
+}
+
+// puts expiring fields of a hash to the heap, all of them for a new item,
+// only those touched by the change for an item changed in place
+func (sh *shard) trackFields(i *item, all bool) {
+	h, ok := i.Value.(*Hash)
+	if !ok {
+		return
+	}
+	if all {
+		for f, t := range h.Expirations {
+			sh.trackField(i.key, f, t)
+		}
+	} else {
+		for _, f := range h.touched {
+			if t, ok := h.Expirations[f]; ok {
+				sh.trackField(i.key, f, t)
+			} else {
+				sh.untrackField(i.key, f)
+			}
+		}
+	}
+	h.touched = nil
+}
+
+func (sh *shard) trackField(key, field string, at time.Time) {
+	fields, ok := sh.tracked[key]
+	if !ok {
+		fields = make(map[string]*fieldExpiration)
+		sh.tracked[key] = fields
+	}
+	if fe, ok := fields[field]; ok {
+		fe.at = at
+		heap.Fix(&sh.fields, fe.index)
+		return
+	}
+	fe := &fieldExpiration{key: key, field: field, at: at}
+	fields[field] = fe
+	heap.Push(&sh.fields, fe)
+}
+
+func (sh *shard) untrackField(key, field string) {
+	fe, ok := sh.tracked[key][field]
+	if !ok {
+		return
+	}
+	heap.Remove(&sh.fields, fe.index)
+	delete(sh.tracked[key], field)
+	if len(sh.tracked[key]) == 0 {
+		delete(sh.tracked, key)
+	}
+}
+
+func (sh *shard) update(key string, i *item) error {
+	_, err := sh.get(key)
+	if err == nil {
+		sh.set(key, i)
+	}
+	return err
+}
+
+func (sh *shard) delete(key string) {
+	sh.remove(key, EventDelete)
+}
+
+// removes the item and tells watchers why it is gone
+func (sh *shard) remove(key string, reason EventType) {
+	if old := sh.detach(key); old != nil {
+		if reason == EventDelete && old.isExpired() { // was not removed by expire yet
+			reason = EventExpire
+		}
+		sh.emit(reason, key, old.Version, nil, nil)
+	}
+}
+
+// removes the item without events, returns it or nil if it was not there
+func (sh *shard) detach(key string) *item {
+	old, ok := sh.items[key]
+	if !ok {
+		return nil
+	}
+	if old.index >= 0 {
+		heap.Remove(&sh.expiring, old.index)
+	}
+	delete(sh.items, key)
+	sh.ordered.delete(key, 0)
+	for _, fe := range sh.tracked[key] {
+		heap.Remove(&sh.fields, fe.index)
+	}
+	delete(sh.tracked, key)
+	atomic.AddInt64(&sh.usage.items, -1)
+	atomic.AddInt64(&sh.usage.bytes, -old.size)
+	return old
+}
+
+func (sh *shard) keys(keys []string) []string {
+	for key, item := range sh.items {
+		if !item.isExpired() {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,101 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestShards_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+		store.WithShards(4),
+	)
+	for i := 0; i < 100; i++ {
+		s.Set(fmt.Sprintf("key%d", i), i, time.Hour)
+	}
+	s.Stop()
+
+	// the number of shards is not a part of the snapshot
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+		store.WithShards(7),
+	)
+	if l := len(r.Keys()); l != 100 {
+		t.Errorf("Expected 100 keys, but found %v", l)
+	}
+	for i := 0; i < 100; i++ {
+		val, err := r.Get(fmt.Sprintf("key%d", i))
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if val != i {
+			t.Errorf("Expected value is %v, but found %v", i, val)
+		}
+	}
+
+	//teardown
+	r.Stop()
+}
+
+func TestShards_NotPositive(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		s := store.New(
+			store.WithCustomFilename(tempFilename(t)),
+			store.WithShards(n),
+		)
+		s.Set("someKey", 1, time.Hour)
+		if val, err := s.Get("someKey"); err != nil || val != 1 {
+			t.Errorf("Expected value 1 with %v shards, but found %v, %v", n, val, err)
+		}
+		s.Stop()
+	}
+}
+
+// go test -bench=Parallel -cpu=8 ./store
+// reads only contend on shard locks, writes also go through the log which is one for the whole store
+func BenchmarkParallel_ReadHeavy(b *testing.B) {
+	benchmarkParallel(b, 1) // 10% of writes
+}
+
+func BenchmarkParallel_WriteHeavy(b *testing.B) {
+	benchmarkParallel(b, 9) // 90% of writes
+}
+
+func benchmarkParallel(b *testing.B, writes int) {
+	const keys = 10000
+
+	for _, shards := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			s := store.New(
+				store.WithCustomFilename(tempFilename(b)),
+				store.WithShards(shards),
+				store.WithFsyncPolicy(store.FsyncNever),
+			)
+			for i := 0; i < keys; i++ {
+				s.Set(fmt.Sprintf("key%d", i), i, time.Hour)
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				r := rand.New(rand.NewSource(time.Now().UnixNano()))
+				for pb.Next() {
+					key := fmt.Sprintf("key%d", r.Intn(keys))
+					if r.Intn(10) < writes {
+						s.Update(key, 0, time.Hour)
+					} else {
+						s.Get(key)
+					}
+				}
+			})
+			b.StopTimer()
+
+			s.Stop()
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// CountMinSketch estimates how often elements were added in a fixed memory of depth rows of width
+// counters, an estimate is never below the real count and exceeds it by more than error rate of
+// the total count only with the given probability
+
+const (
+	DefSketchErrorRate   = 0.01
+	DefSketchProbability = 0.01
+
+	errSketchParamsFmt = "error rate %v and probability %v must be within (0, 1)"
+)
+
+func init() {
+	gob.Register(&CountMinSketch{})
+	gob.Register(&sketchIncr{})
+}
+
+type CountMinSketch struct {
+	Width  int
+	Depth  int
+	Counts []uint64 // row after row
+	Total  uint64
+}
+
+func newCountMinSketch(errorRate, probability float64) *CountMinSketch {
+	width := int(math.Ceil(math.E / errorRate))
+	depth := int(math.Ceil(math.Log(1 / probability)))
+	return newSketchOf(width, depth)
+}
+
+func newSketchOf(width, depth int) *CountMinSketch {
+	return &CountMinSketch{Width: width, Depth: depth, Counts: make([]uint64, width*depth)}
+}
+
+func validSketch(errorRate, probability float64) bool {
+	return errorRate > 0 && errorRate < 1 && probability > 0 && probability < 1
+}
+
+// never empty, removed only by Delete
+func (c *CountMinSketch) len() int {
+	return 1
+}
+
+func (c *CountMinSketch) clone() interface{} {
+	clone := *c
+	clone.Counts = append([]uint64(nil), c.Counts...)
+	return &clone
+}
+
+// index of the counter of the element in each row
+func (c *CountMinSketch) counters(element string, fn func(n int)) {
+	x := elementHash(element)
+	h1, h2 := x, bits.RotateLeft64(x, 32)|1
+	for row := 0; row < c.Depth; row++ {
+		fn(row*c.Width + int((h1+uint64(row)*h2)%uint64(c.Width)))
+	}
+}
+
+// counters stop at the max value instead of overflow
+func (c *CountMinSketch) incr(element string, delta uint64) uint64 {
+	c.counters(element, func(n int) {
+		c.Counts[n] = saturatingAdd(c.Counts[n], delta)
+	})
+	c.Total = saturatingAdd(c.Total, delta)
+	return c.count(element)
+}
+
+func (c *CountMinSketch) count(element string) uint64 {
+	result := uint64(math.MaxUint64)
+	c.counters(element, func(n int) {
+		if c.Counts[n] < result {
+			result = c.Counts[n]
+		}
+	})
+	return result
+}
+
+func (c *CountMinSketch) merge(other *CountMinSketch) {
+	for n, v := range other.Counts {
+		c.Counts[n] = saturatingAdd(c.Counts[n], v)
+	}
+	c.Total = saturatingAdd(c.Total, other.Total)
+}
+
+func (c *CountMinSketch) compatible(other *CountMinSketch) bool {
+	return c.Width == other.Width && c.Depth == other.Depth
+}
+
+func saturatingAdd(a, b uint64) uint64 {
+	if sum := a + b; sum >= a {
+		return sum
+	}
+	return math.MaxUint64
+}
+
+// creates an empty sketch with the given parameters, the key must not exist
+func (s *Store) CMSInit(key string, errorRate, probability float64) error {
+	if !validSketch(errorRate, probability) {
+		return fmt.Errorf(errSketchParamsFmt, errorRate, probability)
+	}
+	created := false
+	return s.change(key, func() interface{} {
+		created = true
+		return newCountMinSketch(errorRate, probability)
+	}, func(val interface{}) (int64, error) {
+		if !created {
+			return 0, fmt.Errorf(errKeyExistsFmt, key)
+		}
+		return 0, nil
+	})
+}
+
+// counts each of the elements once and returns their estimated counts, a missing key is created
+// with DefSketchErrorRate and DefSketchProbability
+func (s *Store) CMSAdd(key string, elements ...string) ([]uint64, error) {
+	m := &sketchIncr{Elements: elements, Delta: 1}
+	err := s.changeSketch(key, m)
+	return m.counts, err
+}
+
+// returns estimated count of the element after the increment
+func (s *Store) CMSIncrBy(key, element string, delta uint64) (uint64, error) {
+	m := &sketchIncr{Elements: []string{element}, Delta: delta}
+	if err := s.changeSketch(key, m); err != nil {
+		return 0, err
+	}
+	return m.counts[0], nil
+}
+
+func (s *Store) changeSketch(key string, m *sketchIncr) error {
+	return s.mutate(key, func() interface{} {
+		return newCountMinSketch(DefSketchErrorRate, DefSketchProbability)
+	}, m)
+}
+
+// adds delta to each of the elements
+type sketchIncr struct {
+	Elements []string
+	Delta    uint64
+	counts   []uint64
+}
+
+func (m *sketchIncr) apply(key string, val interface{}) (int64, error) {
+	c, ok := val.(*CountMinSketch)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	m.counts = make([]uint64, len(m.Elements))
+	for n, e := range m.Elements {
+		m.counts[n] = c.incr(e, m.Delta)
+	}
+	return 0, nil
+}
+
+// estimated counts of the elements, missing key has no elements
+func (s *Store) CMSQuery(key string, elements ...string) ([]uint64, error) {
+	counts := make([]uint64, len(elements))
+	err := s.read(key, func(val interface{}) error {
+		if val == nil {
+			return nil
+		}
+		c, ok := val.(*CountMinSketch)
+		if !ok {
+			return fmt.Errorf(errWrongTypeFmt, key)
+		}
+		for n, e := range elements {
+			counts[n] = c.count(e)
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// adds counts of the keys to dest, all of them must have the same width and depth,
+// a missing dest is created with dimensions of the keys
+func (s *Store) CMSMerge(dest string, keys ...string) error {
+	var union *CountMinSketch
+	err := s.readMany(keys, func(vals []interface{}) error {
+		for n, val := range vals {
+			if val == nil {
+				continue
+			}
+			c, ok := val.(*CountMinSketch)
+			if !ok {
+				return fmt.Errorf(errWrongTypeFmt, keys[n])
+			}
+			if union == nil {
+				union = newSketchOf(c.Width, c.Depth)
+			}
+			if !union.compatible(c) {
+				return fmt.Errorf(errIncompatibleFmt, keys[n])
+			}
+			union.merge(c)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if union == nil {
+		union = newCountMinSketch(DefSketchErrorRate, DefSketchProbability)
+	}
+
+	return s.change(dest, func() interface{} {
+		return newSketchOf(union.Width, union.Depth)
+	}, func(val interface{}) (int64, error) {
+		c, ok := val.(*CountMinSketch)
+		if !ok {
+			return 0, fmt.Errorf(errWrongTypeFmt, dest)
+		}
+		if !c.compatible(union) {
+			return 0, fmt.Errorf(errIncompatibleFmt, dest)
+		}
+		c.merge(union)
+		return 0, nil
+	})
+}
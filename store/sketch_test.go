@@ -0,0 +1,127 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"testing"
+)
+
+func TestCMSAddCMSQuery(t *testing.T) {
+	s := newStore(t)
+
+	counts, err := s.CMSAdd("hits", "alice", "bob", "alice")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if counts[0] != 1 || counts[1] != 1 || counts[2] != 2 {
+		t.Errorf("Expected counts are [1 1 2], but found %v", counts)
+	}
+
+	n, err := s.CMSIncrBy("hits", "bob", 10)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != 11 {
+		t.Errorf("Expected count is 11, but found %v", n)
+	}
+
+	counts, _ = s.CMSQuery("hits", "alice", "bob", "carol")
+	if counts[0] != 2 || counts[1] != 11 || counts[2] != 0 {
+		t.Errorf("Expected counts are [2 11 0], but found %v", counts)
+	}
+	if counts, _ = s.CMSQuery("nonExisting", "alice"); counts[0] != 0 {
+		t.Errorf("Expected count is 0, but found %v", counts[0])
+	}
+}
+
+func TestCMSInit(t *testing.T) {
+	s := newStore(t)
+
+	if err := s.CMSInit("hits", 0.001, 0.01); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	val, _ := s.Get("hits")
+	if c := val.(*store.CountMinSketch); c.Width != 2719 || c.Depth != 5 {
+		t.Errorf("Expected 2719x5 counters, but found %vx%v", c.Width, c.Depth)
+	}
+
+	err := s.CMSInit("hits", 0.001, 0.01)
+	expected := "key 'hits' already exists"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+	if err = s.CMSInit("other", 0.001, 0); err == nil {
+		t.Errorf("Expected error for probability 0")
+	}
+}
+
+// estimates are never below actual counts and rarely above them by more than error rate of the total
+func TestCountMinSketch_Accuracy(t *testing.T) {
+	s := newStore(t)
+	s.CMSInit("hits", 0.001, 0.01)
+
+	actual := make(map[string]uint64)
+	var total uint64
+	for n := 0; n < 2000; n++ {
+		e := fmt.Sprintf("user%d", n)
+		actual[e] = uint64(n%50 + 1)
+		total += actual[e]
+		s.CMSIncrBy("hits", e, actual[e])
+	}
+
+	exceeded := 0
+	for e, count := range actual {
+		estimates, _ := s.CMSQuery("hits", e)
+		if estimates[0] < count {
+			t.Errorf("Expected estimate of %v is at least %v, but found %v", e, count, estimates[0])
+		}
+		if float64(estimates[0]-count) > 0.001*float64(total) {
+			exceeded++
+		}
+	}
+	if exceeded > 20 {
+		t.Errorf("Expected at most 1%% of estimates above the error, but found %v", exceeded)
+	}
+}
+
+func TestCMSMerge(t *testing.T) {
+	s := newStore(t)
+	s.CMSAdd("monday", "alice", "bob")
+	s.CMSAdd("tuesday", "alice")
+
+	if err := s.CMSMerge("week", "monday", "tuesday"); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if counts, _ := s.CMSQuery("week", "alice", "bob"); counts[0] != 2 || counts[1] != 1 {
+		t.Errorf("Expected counts are [2 1], but found %v", counts)
+	}
+
+	s.CMSInit("other", 0.001, 0.01)
+	err := s.CMSMerge("week", "other")
+	expected := "key 'week' has different parameters"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestCountMinSketch_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.CMSIncrBy("hits", "alice", 5)
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.CMSAdd("hits", "alice")
+	if counts, _ := r.CMSQuery("hits", "alice"); counts[0] != 6 {
+		t.Errorf("Expected count is 6, but found %v", counts[0])
+	}
+
+	//teardown
+	r.Stop()
+}
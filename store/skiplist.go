@@ -0,0 +1,180 @@
+package store
+
+import "math/rand"
+
+// skip list ordered by score and then by member, every link knows how many nodes it jumps over,
+// so rank lookups are O(log n) as well
+
+const (
+	skipListMaxLevel = 32
+	skipListP        = 0.25
+)
+
+type skipNode struct {
+	member string
+	score  float64
+	levels []skipLevel
+}
+
+type skipLevel struct {
+	next *skipNode
+	span int
+}
+
+type skipList struct {
+	head   *skipNode
+	level  int
+	length int
+}
+
+func newSkipList() *skipList {
+	return &skipList{
+		head:  &skipNode{levels: make([]skipLevel, skipListMaxLevel)},
+		level: 1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skipListMaxLevel && rand.Float64() < skipListP {
+		level++
+	}
+	return level
+}
+
+// true if node goes before score and member
+func (node *skipNode) before(score float64, member string) bool {
+	return node.score < score || (node.score == score && node.member < member)
+}
+
+// true if node goes after score and member
+func (node *skipNode) after(score float64, member string) bool {
+	return node.score > score || (node.score == score && node.member > member)
+}
+
+// member must not be in the list
+func (l *skipList) insert(member string, score float64) {
+	var update [skipListMaxLevel]*skipNode
+	var rank [skipListMaxLevel]int
+
+	x := l.head
+	for n := l.level - 1; n >= 0; n-- {
+		if n < l.level-1 {
+			rank[n] = rank[n+1]
+		}
+		for x.levels[n].next != nil && x.levels[n].next.before(score, member) {
+			rank[n] += x.levels[n].span
+			x = x.levels[n].next
+		}
+		update[n] = x
+	}
+
+	level := randomLevel()
+	if level > l.level {
+		for n := l.level; n < level; n++ {
+			update[n] = l.head
+			update[n].levels[n].span = l.length
+		}
+		l.level = level
+	}
+
+	x = &skipNode{member: member, score: score, levels: make([]skipLevel, level)}
+	for n := 0; n < level; n++ {
+		x.levels[n].next = update[n].levels[n].next
+		update[n].levels[n].next = x
+		x.levels[n].span = update[n].levels[n].span - (rank[0] - rank[n])
+		update[n].levels[n].span = rank[0] - rank[n] + 1
+	}
+	for n := level; n < l.level; n++ {
+		update[n].levels[n].span++
+	}
+	l.length++
+}
+
+func (l *skipList) delete(member string, score float64) bool {
+	var update [skipListMaxLevel]*skipNode
+
+	x := l.head
+	for n := l.level - 1; n >= 0; n-- {
+		for x.levels[n].next != nil && x.levels[n].next.before(score, member) {
+			x = x.levels[n].next
+		}
+		update[n] = x
+	}
+
+	x = x.levels[0].next
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+	for n := 0; n < l.level; n++ {
+		if update[n].levels[n].next == x {
+			update[n].levels[n].span += x.levels[n].span - 1
+			update[n].levels[n].next = x.levels[n].next
+		} else {
+			update[n].levels[n].span--
+		}
+	}
+	for l.level > 1 && l.head.levels[l.level-1].next == nil {
+		l.level--
+	}
+	l.length--
+	return true
+}
+
+// 0 based position of the member, -1 if it is not in the list
+func (l *skipList) rank(member string, score float64) int {
+	x := l.head
+	rank := 0
+	for n := l.level - 1; n >= 0; n-- {
+		for x.levels[n].next != nil && !x.levels[n].next.after(score, member) {
+			rank += x.levels[n].span
+			x = x.levels[n].next
+		}
+		if x != l.head && x.member == member && x.score == score {
+			return rank - 1
+		}
+	}
+	return -1
+}
+
+// node at 0 based position, nil if it is out of the list
+func (l *skipList) byRank(rank int) *skipNode {
+	if rank < 0 || rank >= l.length {
+		return nil
+	}
+	x := l.head
+	traversed := 0
+	for n := l.level - 1; n >= 0; n-- {
+		for x.levels[n].next != nil && traversed+x.levels[n].span <= rank+1 {
+			traversed += x.levels[n].span
+			x = x.levels[n].next
+		}
+		if traversed == rank+1 {
+			return x
+		}
+	}
+	return nil
+}
+
+// first node with score not less than min
+func (l *skipList) firstFrom(min float64) *skipNode {
+	x := l.head
+	for n := l.level - 1; n >= 0; n-- {
+		for x.levels[n].next != nil && x.levels[n].next.score < min {
+			x = x.levels[n].next
+		}
+	}
+	return x.levels[0].next
+}
+
+// first node which goes after score and member, or is them if inclusive
+func (l *skipList) seek(score float64, member string, inclusive bool) *skipNode {
+	x := l.head
+	for n := l.level - 1; n >= 0; n-- {
+		for next := x.levels[n].next; next != nil && (next.before(score, member) ||
+			!inclusive && !next.after(score, member)); next = x.levels[n].next {
+			x = next
+		}
+	}
+	return x.levels[0].next
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// snapshot file layout: fixed size header followed by gob encoded items, one map per shard
+// the file is written aside and renamed over the old one, so a reader sees either the old or the new snapshot
+
+const snapshotVersion = 2 // version 1 had a single map, which is read the same way
+
+var snapshotMagic = [4]byte{'G', 'P', 'S', 'S'}
+
+type snapshotHeader struct {
+	Magic    [4]byte
+	Version  uint32
+	Count    uint64 // number of items
+	Length   uint64 // payload length in bytes
+	Checksum uint32 // crc32 of the payload
+}
+
+func writeSnapshotFile(filename string, payload []byte, count int) (err error) {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	header := snapshotHeader{
+		Magic:    snapshotMagic,
+		Version:  snapshotVersion,
+		Count:    uint64(count),
+		Length:   uint64(len(payload)),
+		Checksum: crc32.ChecksumIEEE(payload),
+	}
+	if err = binary.Write(tmp, binary.BigEndian, header); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(payload); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// rename is durable only after the directory entry is flushed
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func readSnapshotFile(filename string) (map[string]*item, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var header snapshotHeader
+	err = binary.Read(file, binary.BigEndian, &header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if err != nil || header.Magic != snapshotMagic {
+		return readLegacySnapshot(file)
+	}
+	if header.Version != snapshotVersion && header.Version != 1 {
+		return nil, fmt.Errorf("snapshot %s: unsupported version %d", filename, header.Version)
+	}
+
+	// the length is checked against the file before it is trusted with an allocation
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if remaining := info.Size() - int64(binary.Size(header)); remaining < 0 || header.Length > uint64(remaining) {
+		return nil, fmt.Errorf("snapshot %s: truncated, expected %d bytes of payload", filename, header.Length)
+	}
+	payload := make([]byte, header.Length)
+	if _, err := io.ReadFull(file, payload); err != nil {
+		return nil, fmt.Errorf("snapshot %s: truncated, expected %d bytes of payload", filename, header.Length)
+	}
+	if crc32.ChecksumIEEE(payload) != header.Checksum {
+		return nil, fmt.Errorf("snapshot %s: checksum mismatch", filename)
+	}
+
+	items := make(map[string]*item)
+	decoder := gob.NewDecoder(bytes.NewReader(payload))
+	for {
+		var shard map[string]*item
+		err := decoder.Decode(&shard)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %v", filename, err)
+		}
+		for key, i := range shard {
+			items[key] = i
+		}
+	}
+	if uint64(len(items)) != header.Count {
+		return nil, fmt.Errorf("snapshot %s: expected %d items, but found %d", filename, header.Count, len(items))
+	}
+	return items, nil
+}
+
+// files written before the header was introduced are bare gob encoded maps
+func readLegacySnapshot(file *os.File) (map[string]*item, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	items := make(map[string]*item)
+	if err := gob.NewDecoder(file).Decode(&items); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %v", file.Name(), err)
+	}
+	return items, nil
+}
@@ -1,11 +1,13 @@
 package store
 
 import (
+	"bytes"
 	"encoding/gob"
 	"fmt"
+	"hash/fnv"
 	"os"
-	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,49 +18,125 @@ const (
 	defFilename      = "./store.gob"
 	defExpInterval   = time.Second
 	defFlushInterval = time.Second * 2
-	defFlushCount    = 5
+	defFlushCount    = 1000 // writes are in the log already, snapshot only keeps the replay short
+	defShards        = 16
+
+	NoExpiration time.Duration = 0 // ttl of a key which is stored until deleted
 )
 
+func init() {
+	// concrete types behind interface{} must be known to gob before they reach the log,
+	// the first two are what encoding/json produces for objects and arrays
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(map[string]bool{})
+	gob.Register(map[string]string{})
+	gob.Register(map[string]int32{})
+	gob.Register(map[string]int64{})
+	gob.Register(map[string]float32{})
+	gob.Register(map[string]float64{})
+}
+
 type item struct {
+	lastAccess int64  // unix nanoseconds, updated atomically under read lock
+	hits       uint64 // updated atomically under read lock
+	size       int64
+	key        string
+	index      int         // position in expiration heap, -1 for persistent items
 	Value      interface{} // interface{} says nothing
-	Expiration time.Time
+	Expiration time.Time   // zero for persistent items
+	Version    uint64      // grows with every change of the key
 }
 
 func (item *item) isExpired() bool {
-	return time.Now().After(item.Expiration)
+	if h, ok := item.Value.(*Hash); ok && h.expired() {
+		return true // all fields expired, the hash is gone as a whole
+	}
+	return !item.Expiration.IsZero() && time.Now().After(item.Expiration)
+}
+
+func expiration(ttl time.Duration) time.Time {
+	if ttl == NoExpiration {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// keys set without ttl get the default one of the store
+func (s *Store) expiration(ttl time.Duration) time.Time {
+	if ttl == NoExpiration {
+		ttl = s.defaultTTL
+	}
+	return expiration(ttl)
+}
+
+func (item *item) touch() {
+	atomic.StoreInt64(&item.lastAccess, time.Now().UnixNano())
+	atomic.AddUint64(&item.hits, 1)
 }
 
 type setting func(*Store)
 
 type Store struct {
-	mu                 sync.RWMutex    // https://github.com/golang/go/wiki/MutexOrChannel
-	items              map[string]item // sync.Map could give synchronization out of the box and help to avoid cache contention
-	updates            chan bool
+	shards             []*shard // keyspace is partitioned by hash of the key, each shard has its own lock
+	usage              usage
+	restored           map[string]*item
+	updates            int64     // since the last flush, counted atomically so writers don't queue on a channel
+	full               chan bool // wakes up flushing once updates reach flushingCount
 	stop               chan bool
 	expirationInterval time.Duration
 	flushingInterval   time.Duration
 	flushingCount      int
 	wg                 sync.WaitGroup
 	filename           string
+	wal                *wal
+	fsyncPolicy        FsyncPolicy
+	maxItems           int
+	maxBytes           int64
+	evictionPolicy     EvictionPolicy
+	defaultTTL         time.Duration // of keys set without ttl
+	signals            signals       // wakes up readers blocked on streams
+	watchers           watchers
+	pubsub             pubsub
 }
 
 func New(settings ...setting) *Store {
 	s := &Store{
-		items:              make(map[string]item),
-		updates:            make(chan bool, 5),
+		shards:             make([]*shard, defShards),
+		full:               make(chan bool, 1),
 		stop:               make(chan bool),
 		filename:           defFilename,
 		expirationInterval: defExpInterval,
 		flushingInterval:   defFlushInterval,
 		flushingCount:      defFlushCount,
+		fsyncPolicy:        FsyncEverySecond,
+		evictionPolicy:     EvictLRU,
 	}
 
 	for _, setting := range settings {
 		setting(s)
 	}
 
-	s.wg.Add(1)
+	s.watchers.epoch = uint64(time.Now().UnixNano())
+	for n := range s.shards {
+		s.shards[n] = newShard(&s.usage, &s.watchers)
+		if size := s.watchers.historySize; size > 0 {
+			s.shards[n].history = newHistory(size)
+		}
+	}
+	for key, i := range s.restored {
+		s.shard(key).set(key, i)
+	}
+	s.restored = nil
+
+	s.wal = openWAL(s.filename, s.fsyncPolicy)
+	// the run starts from a snapshot of its own, so segments of a previous run, which may have been
+	// started without restore, are never replayed over versions given by this one
+	s.flush()
+
+	s.wg.Add(2)
 	go s.runFlushing()
+	go s.runSyncing()
 	go s.runExpiration()
 	return s
 }
@@ -69,205 +147,207 @@ func WithCustomFilename(filename string) setting {
 	}
 }
 
+// restores the last snapshot and replays the log written after it
 func WithRestoreFromFile(filename string) setting {
 	return func(s *Store) {
-		s.items = load(filename)
+		s.restored = load(filename)
+		replay(filename, s.restored)
+	}
+}
+
+func WithFsyncPolicy(policy FsyncPolicy) setting {
+	return func(s *Store) {
+		s.fsyncPolicy = policy
+	}
+}
+
+// keys set with NoExpiration expire after ttl, Persist still keeps a key until deleted
+func WithDefaultTTL(ttl time.Duration) setting {
+	return func(s *Store) {
+		s.defaultTTL = ttl
+	}
+}
+
+// n <= 0 keeps the default number of shards
+func WithShards(n int) setting {
+	return func(s *Store) {
+		if n > 0 {
+			s.shards = make([]*shard, n)
+		}
 	}
 }
 
 func (s *Store) Stop() {
-	s.stop <- true
-	s.stop <- true // looks strange, change to close(s.stop)
+	close(s.stop)
 	s.wg.Wait()
+	s.wal.close()
+	s.watchers.closeAll()
+	s.pubsub.closeAll()
 }
 
-func (s *Store) Get(key string) (interface{}, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock() // gives performance overhead
-
-	return s.get(key)
+// stops the store and removes its snapshot and log
+func (s *Store) Drop() {
+	s.Stop()
+	RemoveFiles(s.filename)
 }
 
-func (s *Store) get(key string) (interface{}, error) {
-	i, ok := s.items[key]
-	if ok && !i.isExpired() {
-		return i.Value, nil
+// removes snapshot and log of a store which is not running
+func RemoveFiles(filename string) {
+	for _, seq := range segments(filename) {
+		os.Remove(segmentName(filename, seq))
 	}
-	return nil, fmt.Errorf(errKeyNotFoundFmt, key)
+	os.Remove(filename)
+}
+
+func (s *Store) shard(key string) *shard {
+	return s.shards[s.shardIndex(key)]
+}
+
+func (s *Store) shardIndex(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(s.shards)))
 }
 
+func (s *Store) Get(key string) (interface{}, error) {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock() // gives performance overhead
+
+	return sh.get(key)
+}
+
+// panics for values of types gob doesn't know before anything is changed, they can't go to the log,
+// such types should be registered with Register
 func (s *Store) Set(key string, value interface{}, ttl time.Duration) {
-	i := item{
+	encoded, err := encodeValue(value)
+	if err != nil {
+		panic(err)
+	}
+	i := &item{
 		Value:      value,
-		Expiration: time.Now().Add(ttl),
+		Expiration: s.expiration(ttl),
 	}
 
-	s.mu.Lock()
-	s.set(key, i)
-	s.mu.Unlock()
-
-	s.updates <- true
-}
+	sh := s.shard(key)
+	sh.locked(func() error {
+		sh.set(key, i)
+		s.wal.appendEncoded(key, *i, encoded)
+		return nil
+	})
 
-func (s *Store) set(key string, i item) {
-	s.items[key] = i
+	s.evict()
+	s.updated()
 }
 
+// fails for values of types gob doesn't know, as Set panics for them
 func (s *Store) Update(key string, value interface{}, ttl time.Duration) error {
-	i := item{
+	encoded, err := encodeValue(value)
+	if err != nil {
+		return err
+	}
+	i := &item{
 		Value:      value,
-		Expiration: time.Now().Add(ttl),
+		Expiration: s.expiration(ttl),
 	}
 
-	s.mu.Lock()
-	err := s.update(key, i)
-	s.mu.Unlock()
+	sh := s.shard(key)
+	err = sh.locked(func() error {
+		if err := sh.update(key, i); err != nil {
+			return err
+		}
+		s.wal.appendEncoded(key, *i, encoded)
+		return nil
+	})
 
-	s.updates <- true
+	s.evict()
+	s.updated()
 
 	return err
 }
 
-func (s *Store) update(key string, i item) error {
-	_, err := s.get(key)
-	if err == nil {
-		s.items[key] = i
-	}
-	return err
-}
-
 func (s *Store) Delete(key string) {
-	s.mu.Lock()
-	s.delete(key)
-	s.mu.Unlock()
-
-	s.updates <- true
-}
-
-func (s *Store) delete(key string) {
-	delete(s.items, key)
+	sh := s.shard(key)
+	sh.locked(func() error {
+		sh.delete(key)
+		s.wal.append(opDelete, key, item{})
+		return nil
+	})
+
+	s.updated()
 }
 
 func (s *Store) Keys() []string {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	return s.keys()
-}
-
-func (s *Store) keys() []string {
-	keys := make([]string, 0, len(s.items))
-	for key, item := range s.items {
-		if !item.isExpired() {
-			keys = append(keys, key)
-		}
+	keys := make([]string, 0, s.usage.count())
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		keys = sh.keys(keys)
+		sh.mu.RUnlock()
 	}
 	return keys
 }
 
-func (s *Store) GetMapEntry(key, innerKey string) (interface{}, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	return s.getMapEntry(key, innerKey)
+type Stats struct {
+	Items       int    `json:"items"`
+	Bytes       int64  `json:"bytes"` // estimated
+	Evictions   uint64 `json:"evictions"`
+	Expirations uint64 `json:"expirations"`
 }
 
-// option 1 - simple and stupid
-/*func (s *Store) getMapEntry(key, innerKey string) (interface{}, error) {
-	val, err := s.get(key)
-	if err != nil {
-		return nil, err
+func (s *Store) Stats() Stats {
+	return Stats{
+		Items:       int(s.usage.count()),
+		Bytes:       s.usage.size(),
+		Evictions:   atomic.LoadUint64(&s.usage.evictions),
+		Expirations: atomic.LoadUint64(&s.usage.expirations),
 	}
+}
 
+// entry of a hash or of a map value written by Set, HGet is the way to go for hashes
+func (s *Store) GetMapEntry(key, innerKey string) (interface{}, error) {
 	var result interface{}
-	var ok bool
-
-	switch val.(type) {
-	case map[string]bool:
-		result, ok = val.(map[string]bool)[innerKey]
-	case map[string]string:
-		result, ok = val.(map[string]string)[innerKey]
-	case map[string]int32:
-		result, ok = val.(map[string]int32)[innerKey]
-	case map[string]int64:
-		result, ok = val.(map[string]int64)[innerKey]
-	case map[string]float32:
-		result, ok = val.(map[string]float32)[innerKey]
-	case map[string]float64:
-		result, ok = val.(map[string]float64)[innerKey]
-	default:
-		return nil, fmt.Errorf(errWrongTypeFmt, key)
-	}
-
-	if !ok {
-		return nil, fmt.Errorf(errKeyNotFoundFmt, innerKey)
-	}
-
-	return result, nil
-}*/
-
-// option 2 - O(N)
-/*func (s *Store) getMapEntry(key, innerKey string) (interface{}, error) {
-	val, err := s.get(key)
-	if err != nil {
-		return nil, err
-	}
-
-	m := reflect.ValueOf(val)
-	if m.Kind() == reflect.Map {
-		for _, key := range m.MapKeys() {
-			if key.Interface() == innerKey {
-				return m.MapIndex(key).Interface(), nil
-			}
+	err := s.read(key, func(val interface{}) error {
+		if val == nil {
+			return fmt.Errorf(errKeyNotFoundFmt, key)
 		}
-		return nil, fmt.Errorf(errKeyNotFoundFmt, innerKey)
-	} else {
-		return nil, fmt.Errorf(errWrongTypeFmt, key)
-	}
-}*/
 
-// option 3
-func (s *Store) getMapEntry(key, innerKey string) (result interface{}, err error) {
-	val, err := s.get(key)
-	if err != nil {
-		return nil, err
-	}
-
-	// deferred recover works only with naked return?
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf(errKeyNotFoundFmt, innerKey)
+		var ok bool
+		switch m := val.(type) {
+		case *Hash:
+			result, ok = m.get(innerKey)
+		case map[string]interface{}:
+			result, ok = m[innerKey]
+		case map[string]bool:
+			result, ok = m[innerKey]
+		case map[string]string:
+			result, ok = m[innerKey]
+		case map[string]int32:
+			result, ok = m[innerKey]
+		case map[string]int64:
+			result, ok = m[innerKey]
+		case map[string]float32:
+			result, ok = m[innerKey]
+		case map[string]float64:
+			result, ok = m[innerKey]
+		default:
+			return fmt.Errorf(errWrongTypeFmt, key)
 		}
-	}()
-
-	m := reflect.ValueOf(val)
-	if m.Kind() == reflect.Map {
-		result = m.MapIndex(reflect.ValueOf(innerKey)).Interface()
-	} else {
-		err = fmt.Errorf(errWrongTypeFmt, key)
-	}
-	return
-}
 
-func (s *Store) runExpiration() {
-	c := time.Tick(s.expirationInterval)
-	for {
-		select {
-		case <-c:
-			s.expire()
-		case <-s.stop:
-			return
+		if !ok {
+			result = nil
+			return fmt.Errorf(errKeyNotFoundFmt, innerKey)
 		}
-	}
+		return nil
+	})
+	return result, err
 }
 
-func (s *Store) expire() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	for key, item := range s.items {
-		if item.isExpired() {
-			delete(s.items, key)
+// counts a write, exactly one writer reaches flushingCount and wakes up flushing
+func (s *Store) updated() {
+	if atomic.AddInt64(&s.updates, 1) == int64(s.flushingCount) {
+		select {
+		case s.full <- true:
+		default:
 		}
 	}
 }
@@ -277,23 +357,23 @@ func (s *Store) runFlushing() {
 	defer s.wg.Done()
 
 	timer := time.NewTimer(s.flushingInterval) // do I need defer timer.Stop() here?
-	counter := 0
 
 	flushAndReset := func() {
+		atomic.StoreInt64(&s.updates, 0) // before the flush, writes made during it go to the next one
 		s.flush()
 		timer.Reset(s.flushingInterval)
-		counter = 0
 	}
 
 	for {
 		select {
 		case <-timer.C:
-			flushAndReset()
-		case <-s.updates:
-			counter++
-			if counter >= s.flushingCount {
+			if atomic.LoadInt64(&s.updates) > 0 {
 				flushAndReset()
+			} else {
+				timer.Reset(s.flushingInterval)
 			}
+		case <-s.full:
+			flushAndReset()
 		case <-s.stop:
 			flushAndReset()
 			return
@@ -301,36 +381,58 @@ func (s *Store) runFlushing() {
 	}
 }
 
+// fsyncs the log once a second for FsyncEverySecond policy
+func (s *Store) runSyncing() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(walSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.wal.sync()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// writes a snapshot and drops the part of the log it covers
 func (s *Store) flush() {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	covered := s.wal.rotate()
+	s.writeSnapshot()
+	s.wal.truncate(covered)
+}
 
-	file, err := os.Create(s.filename)
-	if err != nil {
-		panic(err)
+// every shard is encoded under its own lock, disk io doesn't need any
+func (s *Store) writeSnapshot() {
+	var payload bytes.Buffer
+	encoder := gob.NewEncoder(&payload)
+	count := 0
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		err := encoder.Encode(sh.items)
+		count += len(sh.items)
+		sh.mu.RUnlock()
+
+		if err != nil {
+			panic(err)
+		}
 	}
-	defer file.Close()
 
-	encoder := gob.NewEncoder(file)
-	err = encoder.Encode(s.items)
-	if err != nil {
+	if err := writeSnapshotFile(s.filename, payload.Bytes(), count); err != nil {
 		panic(err)
 	}
 }
 
-func load(filename string) map[string]item {
-	file, err := os.Open(filename)
-	if err != nil {
-		panic(err)
+func load(filename string) map[string]*item {
+	items, err := readSnapshotFile(filename)
+	if os.IsNotExist(err) {
+		return make(map[string]*item) // nothing was snapshotted yet, everything is in the log
 	}
-	defer file.Close()
-
-	decoder := gob.NewDecoder(file)
-	m := make(map[string]item)
-
-	err = decoder.Decode(&m)
 	if err != nil {
 		panic(err)
 	}
-	return m
+	return items
 }
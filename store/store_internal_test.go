@@ -3,29 +3,320 @@ package store
 // https://medium.com/@matryer/5-simple-tips-and-tricks-for-writing-unit-tests-in-golang-619653f90742
 
 import (
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
 
 func TestSet(t *testing.T) {
-	store := New()
+	store := New(WithCustomFilename(filepath.Join(t.TempDir(), "store.gob")))
+	defer store.Stop()
 	store.Set("someKey", 123, time.Second)
 
-	item := store.items["someKey"]
+	sh := store.shard("someKey")
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	item := sh.items["someKey"]
 	if item.Value != 123 {
 		t.Errorf("Expected value is %v, but found %v", 123, item.Value)
 	}
-	if l := len(store.items); l != 1 {
+	if l := len(sh.items); l != 1 {
 		t.Errorf("Expected len of internal map is 1, but found %v", l)
 	}
 }
 
 func TestExpirationRemovesExpiredItems(t *testing.T) {
-	store := New()
+	store := New(WithCustomFilename(filepath.Join(t.TempDir(), "store.gob")))
+	defer store.Stop()
 	store.Set("someKey", 123, time.Second)
 	time.Sleep(time.Second * 2)
 
-	if l := len(store.items); l != 0 {
+	sh := store.shard("someKey")
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	if l := len(sh.items); l != 0 {
+		t.Errorf("Expected len of internal map is 0, but found %v", l)
+	}
+}
+
+func TestReplay_TornTail(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.gob")
+
+	w := openWAL(filename, FsyncAlways)
+	w.append(opSet, "key1", item{Value: 1, Expiration: time.Now().Add(time.Hour)})
+	w.append(opSet, "key2", item{Value: 2, Expiration: time.Now().Add(time.Hour)})
+	w.close()
+
+	// cut the last record in the middle as a crash during write would do
+	name := segmentName(filename, 1)
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(name, info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+
+	items := make(map[string]*item)
+	replay(filename, items)
+	if l := len(items); l != 1 {
+		t.Errorf("Expected len of restored map is 1, but found %v", l)
+	}
+	if val := items["key1"].Value; val != 1 {
+		t.Errorf("Expected value is %v, but found %v", 1, val)
+	}
+}
+
+// pushes go to the log as mutations, replay skips those which the items have already
+func TestReplay_Mutations(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.gob")
+	noFlushing := func(s *Store) {
+		s.flushingInterval, s.flushingCount = time.Hour, 10000
+	}
+	s := New(WithCustomFilename(filename), WithFsyncPolicy(FsyncNever), noFlushing)
+	for n := 0; n < 5000; n++ {
+		s.RPush("someList", n)
+	}
+	s.wal.close()
+	defer s.Stop()
+
+	if size := logSize(t, filename); size > 1<<20 {
+		t.Errorf("Expected log of pushes to be under 1MB, but found %v bytes", size)
+	}
+
+	items := make(map[string]*item)
+	replay(filename, items)
+	replay(filename, items) // as if the snapshot had all of it already
+	l, ok := items["someList"].Value.(*List)
+	if !ok || len(l.Items) != 5000 || l.Items[4999] != 4999 {
+		t.Errorf("Expected list of 5000 items, but found %v", items["someList"].Value)
+	}
+}
+
+func TestReplay_PreviousRun(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.gob")
+	noFlushing := func(s *Store) {
+		s.flushingInterval, s.flushingCount = time.Hour, 10000
+	}
+	s := New(WithCustomFilename(filename))
+	s.Set("oldKey", 1, time.Hour)
+	s.RPush("someList", "a", "b", "c")
+	s.Stop()
+
+	// started without restore, crashed before the first flush
+	s = New(WithCustomFilename(filename), noFlushing)
+	s.RPush("someList", "d")
+	s.wal.close()
+	defer s.Stop()
+
+	r := New(WithCustomFilename(filepath.Join(t.TempDir(), "other.gob")), WithRestoreFromFile(filename))
+	defer r.Stop()
+	if _, err := r.Get("oldKey"); err == nil {
+		t.Errorf("Expected the key of the previous run to be gone")
+	}
+	if l, err := r.LRange("someList", 0, -1); err != nil || len(l) != 1 || l[0] != "d" {
+		t.Errorf("Expected the list of the last run, but found %v, %v", l, err)
+	}
+}
+
+func TestReplay_SetBit(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.gob")
+	noFlushing := func(s *Store) {
+		s.flushingInterval, s.flushingCount = time.Hour, 10000
+	}
+	s := New(WithCustomFilename(filename), WithFsyncPolicy(FsyncNever), noFlushing)
+	s.SetBit("someBitmap", 8<<20, 1) // a megabyte value
+	for n := 0; n < 100; n++ {
+		s.SetBit("someBitmap", n, 1)
+	}
+	s.wal.close()
+	defer s.Stop()
+
+	if size := logSize(t, filename); size > 1<<16 {
+		t.Errorf("Expected log of bits to be under 64KB, but found %v bytes", size)
+	}
+
+	items := make(map[string]*item)
+	replay(filename, items)
+	b, ok := items["someBitmap"].Value.([]byte)
+	if !ok || len(b) != 1<<20+1 || b[0] != 0xff || b[len(b)-1] != 0x80 {
+		t.Errorf("Expected a bitmap of 1MB and a byte with bits set, but found %v bytes", len(b))
+	}
+}
+
+func logSize(t *testing.T, filename string) int64 {
+	var size int64
+	for _, seq := range segments(filename) {
+		info, err := os.Stat(segmentName(filename, seq))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+	return size
+}
+
+func TestSnapshot_Corrupted(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.gob")
+	s := New(WithCustomFilename(filename))
+	s.Set("someKey", "some_value", time.Hour)
+	s.Stop()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = readSnapshotFile(filename)
+	expected := fmt.Sprintf("snapshot %s: checksum mismatch", filename)
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+
+	if err := os.Truncate(filename, int64(len(data)-10)); err != nil {
+		t.Fatal(err)
+	}
+	_, err = readSnapshotFile(filename)
+	if err == nil {
+		t.Errorf("Expected error for truncated snapshot, but found nil")
+	}
+
+	binary.BigEndian.PutUint64(data[16:24], 1<<62) // length of the payload in the header
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = readSnapshotFile(filename)
+	expected = fmt.Sprintf("snapshot %s: truncated, expected %d bytes of payload", filename, uint64(1<<62))
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestSnapshot_Legacy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.gob")
+	file, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := map[string]item{"someKey": {Value: 123, Expiration: time.Now().Add(time.Hour)}}
+	if err := gob.NewEncoder(file).Encode(items); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	restored, err := readSnapshotFile(filename)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if val := restored["someKey"].Value; val != 123 {
+		t.Errorf("Expected value is %v, but found %v", 123, val)
+	}
+}
+
+func TestExpirationHeap(t *testing.T) {
+	store := New(WithShards(1), WithCustomFilename(filepath.Join(t.TempDir(), "store.gob")))
+	defer store.Stop()
+	store.Set("key1", 1, time.Hour)
+	store.Set("key2", 2, time.Millisecond)
+	store.Set("key3", 3, time.Minute)
+	store.Set("key1", 4, time.Millisecond) // replaces the old expiration
+	store.Delete("key3")
+
+	sh := store.shards[0]
+	sh.mu.RLock()
+	if l := len(sh.expiring); l != 2 {
+		t.Errorf("Expected len of expiration heap is 2, but found %v", l)
+	}
+	sh.mu.RUnlock()
+
+	time.Sleep(time.Millisecond * 5)
+	store.expire()
+
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	if l := len(sh.expiring); l != 0 {
+		t.Errorf("Expected len of expiration heap is 0, but found %v", l)
+	}
+	if l := len(sh.items); l != 0 {
 		t.Errorf("Expected len of internal map is 0, but found %v", l)
 	}
 }
+
+func TestFieldExpirationHeap(t *testing.T) {
+	store := New(WithShards(1), WithCustomFilename(filepath.Join(t.TempDir(), "store.gob")))
+	defer store.Stop()
+	// ttls can't pass during the test, fields are expired on purpose below
+	store.HSetEx("hash1", "f1", 1, time.Hour)
+	store.HSetEx("hash1", "f2", 2, time.Hour)
+	store.HSetEx("hash1", "f3", 3, time.Hour)
+	store.HSetEx("hash2", "f1", 1, time.Hour)
+	store.HSetEx("hash3", "f1", 1, time.Hour)
+	store.HPersist("hash1", "f2")
+	store.HDel("hash1", "f3")
+	store.Delete("hash3")
+
+	sh := store.shards[0]
+	sh.mu.Lock()
+	if l := len(sh.fields); l != 2 {
+		t.Errorf("Expected len of field expiration heap is 2, but found %v", l)
+	}
+	past := time.Now().Add(-time.Second)
+	for _, fe := range sh.fields {
+		fe.at = past
+		sh.items[fe.key].Value.(*Hash).Expirations[fe.field] = past
+	}
+	sh.mu.Unlock()
+
+	store.expire()
+
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	if l := len(sh.fields); l != 0 {
+		t.Errorf("Expected len of field expiration heap is 0, but found %v", l)
+	}
+	if l := len(sh.tracked); l != 0 {
+		t.Errorf("Expected len of tracked fields is 0, but found %v", l)
+	}
+	if _, ok := sh.items["hash2"]; ok {
+		t.Errorf("Expected hash2 to be removed with its last field")
+	}
+	if h := sh.items["hash1"].Value.(*Hash); len(h.Fields) != 1 {
+		t.Errorf("Expected hash1 to keep 1 field, but found %v", len(h.Fields))
+	}
+}
+
+func TestSkipList(t *testing.T) {
+	l := newSkipList()
+	for n := 0; n < 1000; n++ {
+		l.insert(fmt.Sprintf("m%04d", n), float64(n%100))
+	}
+	for n := 0; n < 1000; n += 2 {
+		l.delete(fmt.Sprintf("m%04d", n), float64(n%100))
+	}
+
+	if l.length != 500 {
+		t.Errorf("Expected length is 500, but found %v", l.length)
+	}
+	// ranks and positions agree, nodes are ordered by score and then by member
+	var prev *skipNode
+	for rank := 0; rank < l.length; rank++ {
+		node := l.byRank(rank)
+		if r := l.rank(node.member, node.score); r != rank {
+			t.Errorf("Expected rank of %v is %v, but found %v", node.member, rank, r)
+		}
+		if prev != nil && !prev.before(node.score, node.member) {
+			t.Errorf("Expected %v to go before %v", prev.member, node.member)
+		}
+		prev = node
+	}
+}
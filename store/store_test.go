@@ -1,9 +1,10 @@
 package store_test
 
 import (
-	"fmt"
+	"context"
 	"github.com/baratov/golang-playground/store"
-	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -11,7 +12,7 @@ import (
 )
 
 func TestGet(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	s.Set("someKey", 123, time.Second)
 
 	val, err := s.Get("someKey")
@@ -24,7 +25,7 @@ func TestGet(t *testing.T) {
 }
 
 func TestGet_NonExistingKey(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 
 	val, err := s.Get("someKey")
 	expected := "key 'someKey' not found"
@@ -37,7 +38,7 @@ func TestGet_NonExistingKey(t *testing.T) {
 }
 
 func TestGet_ExpiredKey(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	s.Set("someKey", 123, time.Second)
 	time.Sleep(time.Second)
 
@@ -51,8 +52,40 @@ func TestGet_ExpiredKey(t *testing.T) {
 	}
 }
 
+type point struct {
+	X, Y int
+}
+
+func TestSet_UnregisteredType(t *testing.T) {
+	s := newStore(t)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected panic for a type gob doesn't know")
+			}
+		}()
+		s.Set("p", point{1, 2}, time.Minute)
+	}()
+	if err := s.Update("p", point{1, 2}, time.Minute); err == nil {
+		t.Errorf("Expected error for a type gob doesn't know")
+	}
+	if _, err := s.Get("p"); err == nil {
+		t.Errorf("Expected the key not to be set")
+	}
+	err := s.Txn(func(tx *store.Tx) error {
+		tx.Set("p", point{1, 2}, time.Minute)
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Expected error of the transaction")
+	}
+	if _, err := s.Get("p"); err == nil {
+		t.Errorf("Expected the key not to be set by the transaction")
+	}
+}
+
 func TestUpdate(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	s.Set("someKey", 123, time.Second)
 
 	err := s.Update("someKey", 234, time.Second)
@@ -70,7 +103,7 @@ func TestUpdate(t *testing.T) {
 }
 
 func TestUpdate_NonExistingKey(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 
 	err := s.Update("someKey", 234, time.Second)
 	expected := "key 'someKey' not found"
@@ -80,7 +113,7 @@ func TestUpdate_NonExistingKey(t *testing.T) {
 }
 
 func TestUpdate_ExpiredKey(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	s.Set("someKey", 123, time.Second)
 	time.Sleep(time.Second)
 
@@ -92,7 +125,7 @@ func TestUpdate_ExpiredKey(t *testing.T) {
 }
 
 func TestDelete(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	s.Set("someKey", 123, time.Second)
 
 	s.Delete("someKey")
@@ -108,7 +141,7 @@ func TestDelete(t *testing.T) {
 }
 
 func TestKeys(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	s.Set("someKey", 123, time.Second)
 	s.Set("otherKey", 234, time.Second)
 	s.Set("someKey", 345, time.Second)
@@ -122,7 +155,7 @@ func TestKeys(t *testing.T) {
 }
 
 func TestKeys_EmptyStore(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 
 	keys := s.Keys()
 	if len(keys) != 0 {
@@ -132,7 +165,7 @@ func TestKeys_EmptyStore(t *testing.T) {
 }
 
 func TestKeys_ExpiredKey(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	s.Set("someKey", 123, time.Second)
 	s.Set("otherKey", 123, 2*time.Second)
 	time.Sleep(time.Second)
@@ -146,7 +179,7 @@ func TestKeys_ExpiredKey(t *testing.T) {
 }
 
 func TestGetMapEntry(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	val := make(map[string]string)
 	val["innerKey"] = "test_value"
 	s.Set("outerKey", val, time.Second)
@@ -161,7 +194,7 @@ func TestGetMapEntry(t *testing.T) {
 }
 
 func TestGetMapEntry_NonExistingKey(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	val := make(map[string]interface{})
 	val["innerKey"] = "test_value"
 	s.Set("outerKey", val, time.Second)
@@ -177,7 +210,7 @@ func TestGetMapEntry_NonExistingKey(t *testing.T) {
 }
 
 func TestGetMapEntry_WrongType(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	s.Set("outerKey", 123, time.Second)
 
 	entry, err := s.GetMapEntry("outerKey", "innerKey")
@@ -191,7 +224,7 @@ func TestGetMapEntry_WrongType(t *testing.T) {
 }
 
 func TestGetMapEntry_NonExistingInnerKey(t *testing.T) {
-	s := store.New()
+	s := newStore(t)
 	val := make(map[string]string)
 	val["innerKey"] = "test_value"
 	s.Set("outerKey", val, time.Second)
@@ -209,7 +242,9 @@ func TestGetMapEntry_NonExistingInnerKey(t *testing.T) {
 func TestConcurrentAccess(t *testing.T) {
 	runtime.GOMAXPROCS(runtime.NumCPU()) //test won't work on single-core CPU
 
-	s := store.New()
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+	)
 
 	for i := 0; i < 100000; i++ {
 		go s.Set("key", i, time.Second)
@@ -223,7 +258,7 @@ func TestConcurrentAccess(t *testing.T) {
 }
 
 func TestFlushingByTimer(t *testing.T) {
-	filename := fmt.Sprintf("./store_%d.gob", time.Now().Unix())
+	filename := tempFilename(t)
 
 	s := store.New(
 		store.WithCustomFilename(filename),
@@ -244,13 +279,12 @@ func TestFlushingByTimer(t *testing.T) {
 	}
 
 	//teardown
-	if err := os.Remove(filename); err != nil {
-		t.Error(err.Error())
-	}
+	s.Stop()
+	r.Stop()
 }
 
 func TestFlushingByUpdatesCount(t *testing.T) {
-	filename := fmt.Sprintf("./store_%d.gob", time.Now().Unix())
+	filename := tempFilename(t)
 
 	s := store.New(
 		store.WithCustomFilename(filename),
@@ -283,13 +317,12 @@ func TestFlushingByUpdatesCount(t *testing.T) {
 	}
 
 	//teardown
-	if err := os.Remove(filename); err != nil {
-		t.Error(err.Error())
-	}
+	s.Stop()
+	r.Stop()
 }
 
 func TestFlushingByStop(t *testing.T) {
-	filename := fmt.Sprintf("./store_%d.gob", time.Now().Unix())
+	filename := tempFilename(t)
 
 	s := store.New(
 		store.WithCustomFilename(filename),
@@ -310,7 +343,172 @@ func TestFlushingByStop(t *testing.T) {
 	}
 
 	//teardown
-	if err := os.Remove(filename); err != nil {
-		t.Error(err.Error())
+	r.Stop()
+}
+
+func TestRestoreFromLog(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+		store.WithFsyncPolicy(store.FsyncAlways),
+	)
+	s.Set("key1", 1, time.Hour)
+	s.Set("key2", 2, time.Hour)
+	s.Update("key1", 3, time.Hour)
+	s.Delete("key2")
+
+	// no snapshot is written yet, everything comes from the log
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	val, err := r.Get("key1")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if val != 3 {
+		t.Errorf("Expected value is 3, but found %v", val)
+	}
+
+	_, err = r.Get("key2")
+	expected := "key 'key2' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+
+	//teardown
+	s.Stop()
+	r.Stop()
+}
+
+// containers changed in place are restored from mutations in the log
+func TestRestoreFromLog_Containers(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+		store.WithFsyncPolicy(store.FsyncAlways),
+	)
+	s.RPush("someList", "a", "b", "c", "d")
+	s.LPush("someList", "z")
+	s.RPop("someList")
+	s.LTrim("someList", 0, 2)
+	s.SAdd("someSet", "a", "b", "c")
+	s.SRem("someSet", "b")
+	s.ZAdd("someZSet", store.ScoredMember{Member: "a", Score: 1}, store.ScoredMember{Member: "b", Score: 2})
+	s.ZIncrBy("someZSet", "a", 5)
+	s.ZRem("someZSet", "b")
+	s.HSet("someHash", "a", "1")
+	s.HSetEx("someHash", "b", "2", time.Hour)
+	s.HIncrBy("someHash", "c", 3)
+	s.HPersist("someHash", "b")
+	s.HDel("someHash", "a")
+	s.XAdd("someStream", map[string]interface{}{"a": "1"}, store.NoMaxLen)
+	s.XGroupCreate("someStream", "group", store.StreamID{})
+	s.XAdd("someStream", map[string]interface{}{"b": "2"}, store.NoMaxLen)
+	s.XReadGroup(context.Background(), "someStream", "group", "consumer", 0, 0)
+	id, _ := s.XAdd("someStream", map[string]interface{}{"c": "3"}, store.NoMaxLen)
+	s.XReadGroup(context.Background(), "someStream", "group", "consumer", 0, 0)
+	s.XAck("someStream", "group", id)
+	s.PFAdd("someHLL", "a", "b")
+	s.PFAdd("someHLL", "c")
+	s.BFAdd("someBloom", "a")
+	s.BFAdd("someBloom", "b")
+	s.CMSAdd("someSketch", "a", "b")
+	s.CMSIncrBy("someSketch", "a", 10)
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	for _, key := range s.Keys() {
+		expected, _ := s.Get(key)
+		if val, err := r.Get(key); err != nil || !reflect.DeepEqual(val, expected) {
+			t.Errorf("Expected value of %v is %v, but found %v %v", key, expected, val, err)
+		}
+	}
+	if l := len(r.Keys()); l != 8 {
+		t.Errorf("Expected 8 keys, but found %v", l)
+	}
+	if ttl, _ := r.HTTL("someHash", "b"); ttl != store.NoExpiration {
+		t.Errorf("Expected persistent field, but found ttl %v", ttl)
+	}
+	expected, _ := s.XPending("someStream", "group")
+	val, _ := r.XPending("someStream", "group")
+	if len(val) != 2 || len(expected) != 2 {
+		t.Errorf("Expected 2 pending entries, but found %v and %v", expected, val)
+	}
+	for n := 0; n < len(val) && n < len(expected); n++ {
+		if val[n].ID != expected[n].ID || !val[n].Delivered.Equal(expected[n].Delivered) {
+			t.Errorf("Expected pending entry %v, but found %v", expected[n], val[n])
+		}
+	}
+
+	//teardown
+	s.Stop()
+	r.Stop()
+}
+
+func TestRestoreFromSnapshotAndLog(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.Set("key1", 1, time.Hour)
+	s.Stop() // snapshot with key1, empty log
+
+	s = store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+		store.WithFsyncPolicy(store.FsyncAlways),
+	)
+	s.Set("key2", 2, time.Hour)
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	for key, expected := range map[string]int{"key1": 1, "key2": 2} {
+		val, err := r.Get(key)
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if val != expected {
+			t.Errorf("Expected value is %v, but found %v", expected, val)
+		}
+	}
+
+	//teardown
+	s.Stop()
+	r.Stop()
+}
+
+// snapshot and log live in a temp dir which is removed after the test
+func tempFilename(t testing.TB) string {
+	return filepath.Join(t.TempDir(), "store.gob")
+}
+
+// a store with the default settings stopped after the test
+func newStore(t testing.TB) *store.Store {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+	)
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func TestDrop(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.Set("someKey", 123, time.Minute)
+	s.Drop()
+
+	if files, _ := filepath.Glob(filename + "*"); len(files) != 0 {
+		t.Errorf("Expected no files, but found %v", files)
 	}
 }
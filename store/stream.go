@@ -0,0 +1,545 @@
+package store
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stream is a native append-only log of entries with ids growing along with the time of XAdd,
+// consumer groups deliver every entry to one of their consumers and keep it pending until it is acked,
+// a stream is removed once it has neither entries nor groups
+
+const (
+	errGroupNotFoundFmt = "group '%v' not found"
+	errGroupExistsFmt   = "group '%v' already exists"
+	errMalformedIDFmt   = "malformed stream id '%v'"
+	errMaxLenFmt        = "maxlen %v is negative"
+
+	NoMaxLen = 0 // keeps all entries of a stream
+
+	streamEntryOverhead   = 16
+	pendingEntryOverhead  = 48
+	consumerGroupOverhead = 32
+)
+
+// as $ and + in redis, the last possible id, XRead and groups created with it wait for entries added later
+var LastStreamID = StreamID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+
+var errNothingToDeliver = errors.New("nothing to deliver")
+
+func init() {
+	gob.Register(&Stream{})
+	gob.Register(&streamAdd{})
+	gob.Register(&streamTrim{})
+	gob.Register(&streamGroup{})
+	gob.Register(&streamDeliver{})
+	gob.Register(&streamAck{})
+}
+
+// unix milliseconds of XAdd and sequence number within the millisecond
+type StreamID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+// accepts ms-seq, ms for ms-0, - for the first possible id and + or $ for the last one
+func ParseStreamID(s string) (StreamID, error) {
+	switch s {
+	case "-":
+		return StreamID{}, nil
+	case "+", "$":
+		return LastStreamID, nil
+	}
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return StreamID{}, fmt.Errorf(errMalformedIDFmt, s)
+	}
+	var seq uint64
+	if hasSeq {
+		if seq, err = strconv.ParseUint(seqPart, 10, 64); err != nil {
+			return StreamID{}, fmt.Errorf(errMalformedIDFmt, s)
+		}
+	}
+	return StreamID{Ms: ms, Seq: seq}, nil
+}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+func (id StreamID) Less(other StreamID) bool {
+	return id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq)
+}
+
+// ids go to json as strings
+func (id StreamID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *StreamID) UnmarshalText(text []byte) error {
+	parsed, err := ParseStreamID(string(text))
+	if err == nil {
+		*id = parsed
+	}
+	return err
+}
+
+type StreamEntry struct {
+	ID     StreamID               `json:"id"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+type PendingEntry struct {
+	ID         StreamID  `json:"id"`
+	Consumer   string    `json:"consumer"`
+	Delivered  time.Time `json:"delivered"` // the last time
+	Deliveries int       `json:"deliveries"`
+}
+
+type ConsumerGroup struct {
+	LastDelivered StreamID
+	Pending       map[string]*PendingEntry // by id
+}
+
+type Stream struct {
+	Entries []StreamEntry // ordered by id
+	LastID  StreamID
+	Groups  map[string]*ConsumerGroup
+}
+
+func newStream() interface{} {
+	return &Stream{Groups: make(map[string]*ConsumerGroup)}
+}
+
+func (st *Stream) len() int {
+	return len(st.Entries) + len(st.Groups)
+}
+
+func (st *Stream) clone() interface{} {
+	return append([]StreamEntry{}, st.Entries...)
+}
+
+func (st *Stream) size() int64 {
+	var size int64
+	for _, e := range st.Entries {
+		size += sizeOfEntry(e)
+	}
+	for name, g := range st.Groups {
+		size += sizeOfGroup(name)
+		for _, p := range g.Pending {
+			size += sizeOfPending(p)
+		}
+	}
+	return size
+}
+
+// id following the last one, time goes back in the ids never
+func (st *Stream) nextID() StreamID {
+	id := StreamID{Ms: uint64(time.Now().UnixNano() / int64(time.Millisecond))}
+	if !st.LastID.Less(id) {
+		id = StreamID{Ms: st.LastID.Ms, Seq: st.LastID.Seq + 1}
+	}
+	return id
+}
+
+// position of the first entry with id greater than the given one
+func (st *Stream) after(id StreamID) int {
+	return sort.Search(len(st.Entries), func(n int) bool {
+		return id.Less(st.Entries[n].ID)
+	})
+}
+
+// position of the first entry with id not less than the given one
+func (st *Stream) from(id StreamID) int {
+	return sort.Search(len(st.Entries), func(n int) bool {
+		return !st.Entries[n].ID.Less(id)
+	})
+}
+
+// appends the entry and drops the oldest ones over maxLen, returns how the size has changed
+func (st *Stream) add(e StreamEntry, maxLen int) int64 {
+	st.Entries = append(st.Entries, e)
+	st.LastID = e.ID
+	_, delta := st.trim(maxLen)
+	return sizeOfEntry(e) + delta
+}
+
+// entries with the ids become pending for the consumer, returns how the size has changed
+func (st *Stream) deliver(g *ConsumerGroup, consumer string, ids []StreamID, delivered time.Time) int64 {
+	if g.Pending == nil { // gob leaves empty maps out
+		g.Pending = make(map[string]*PendingEntry)
+	}
+	var delta int64
+	for _, id := range ids {
+		p := &PendingEntry{ID: id, Consumer: consumer, Delivered: delivered, Deliveries: 1}
+		g.Pending[id.String()] = p
+		delta += sizeOfPending(p)
+	}
+	g.LastDelivered = ids[len(ids)-1]
+	return delta
+}
+
+// drops the oldest entries over maxLen, returns how the size has changed
+func (st *Stream) trim(maxLen int) (int, int64) {
+	n := len(st.Entries) - maxLen
+	if maxLen == NoMaxLen || n <= 0 {
+		return 0, 0
+	}
+	var delta int64
+	for i := 0; i < n; i++ {
+		delta -= sizeOfEntry(st.Entries[i])
+		st.Entries[i] = StreamEntry{} // let fields go with the old backing array
+	}
+	st.Entries = st.Entries[n:]
+	return n, delta
+}
+
+// blocked readers of streams wait for a channel of their key to be closed by the next XAdd
+type signals struct {
+	mu      sync.Mutex
+	waiting map[string]chan struct{}
+}
+
+func (sg *signals) wait(key string) <-chan struct{} {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if sg.waiting == nil {
+		sg.waiting = make(map[string]chan struct{})
+	}
+	ch, ok := sg.waiting[key]
+	if !ok {
+		ch = make(chan struct{})
+		sg.waiting[key] = ch
+	}
+	return ch
+}
+
+func (sg *signals) notify(key string) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if ch, ok := sg.waiting[key]; ok {
+		close(ch)
+		delete(sg.waiting, key)
+	}
+}
+
+// runs fn until it finds something, the time is out or ctx is done, the signal is taken before fn looks,
+// so an entry added in between is not missed
+func (s *Store) block(ctx context.Context, key string, timeout time.Duration, fn func() (bool, error)) error {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		added := s.signals.wait(key)
+		found, err := fn()
+		if found || err != nil || deadline == nil {
+			return err
+		}
+		select {
+		case <-added:
+		case <-deadline:
+			return nil
+		case <-ctx.Done():
+			return nil
+		case <-s.stop:
+			return nil
+		}
+	}
+}
+
+func changeStream(key string, val interface{}, fn func(st *Stream) (int64, error)) (int64, error) {
+	st, ok := val.(*Stream)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	return fn(st)
+}
+
+// appends an entry with a new id, the oldest entries over maxLen are dropped
+func (s *Store) XAdd(key string, fields map[string]interface{}, maxLen int) (StreamID, error) {
+	if maxLen < 0 {
+		return StreamID{}, fmt.Errorf(errMaxLenFmt, maxLen)
+	}
+	m := &streamAdd{Entry: StreamEntry{Fields: fields}, MaxLen: maxLen}
+	err := s.mutate(key, newStream, m)
+	if err == nil {
+		s.signals.notify(key)
+	}
+	return m.Entry.ID, err
+}
+
+// the id comes from the time, it is replayed as it was given
+type streamAdd struct {
+	Entry  StreamEntry
+	MaxLen int
+}
+
+func (m *streamAdd) apply(key string, val interface{}) (int64, error) {
+	return changeStream(key, val, func(st *Stream) (int64, error) {
+		m.Entry.ID = st.nextID()
+		return st.add(m.Entry, m.MaxLen), nil
+	})
+}
+
+func (m *streamAdd) replay(val interface{}) {
+	if st, ok := val.(*Stream); ok {
+		st.add(m.Entry, m.MaxLen)
+	}
+}
+
+// returns number of dropped entries
+func (s *Store) XTrim(key string, maxLen int) (int, error) {
+	if maxLen < 0 {
+		return 0, fmt.Errorf(errMaxLenFmt, maxLen)
+	}
+	m := &streamTrim{MaxLen: maxLen}
+	err := s.mutate(key, nil, m)
+	return m.removed, err
+}
+
+type streamTrim struct {
+	MaxLen  int
+	removed int
+}
+
+func (m *streamTrim) apply(key string, val interface{}) (int64, error) {
+	return changeStream(key, val, func(st *Stream) (int64, error) {
+		var delta int64
+		m.removed, delta = st.trim(m.MaxLen)
+		return delta, nil
+	})
+}
+
+func (s *Store) XLen(key string) (int, error) {
+	n := 0
+	err := s.read(key, func(val interface{}) error {
+		st, err := asStream(key, val)
+		if err == nil {
+			n = len(st.Entries)
+		}
+		return err
+	})
+	return n, err
+}
+
+// entries with ids from start to end inclusive, count 0 returns all of them
+func (s *Store) XRange(key string, start, end StreamID, count int) ([]StreamEntry, error) {
+	result := []StreamEntry{}
+	err := s.read(key, func(val interface{}) error {
+		st, err := asStream(key, val)
+		if err != nil {
+			return err
+		}
+		for _, e := range st.Entries[st.from(start):] {
+			if end.Less(e.ID) || (count > 0 && len(result) == count) {
+				break
+			}
+			result = append(result, e)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// entries with ids greater than after, LastStreamID stands for the last id at the time of the call,
+// waits up to block for new entries if there are none, 0 doesn't wait, a done ctx stops waiting as well
+func (s *Store) XRead(ctx context.Context, key string, after StreamID, count int, block time.Duration) ([]StreamEntry, error) {
+	result := []StreamEntry{}
+	err := s.block(ctx, key, block, func() (bool, error) {
+		err := s.read(key, func(val interface{}) error {
+			st, err := asStream(key, val)
+			if err != nil {
+				return err
+			}
+			if after == LastStreamID {
+				after = st.LastID
+			}
+			result = take(st.Entries[st.after(after):], count)
+			return nil
+		})
+		return len(result) > 0, err
+	})
+	return result, err
+}
+
+func take(entries []StreamEntry, count int) []StreamEntry {
+	if count > 0 && len(entries) > count {
+		entries = entries[:count]
+	}
+	return append([]StreamEntry{}, entries...)
+}
+
+// creates the stream if it doesn't exist, the group gets entries with ids greater than start
+func (s *Store) XGroupCreate(key, group string, start StreamID) error {
+	return s.mutate(key, newStream, &streamGroup{Name: group, Start: start})
+}
+
+type streamGroup struct {
+	Name  string
+	Start StreamID
+}
+
+func (m *streamGroup) apply(key string, val interface{}) (int64, error) {
+	return changeStream(key, val, func(st *Stream) (int64, error) {
+		if _, ok := st.Groups[m.Name]; ok {
+			return 0, fmt.Errorf(errGroupExistsFmt, m.Name)
+		}
+		if m.Start == LastStreamID {
+			m.Start = st.LastID
+		}
+		if st.Groups == nil {
+			st.Groups = make(map[string]*ConsumerGroup)
+		}
+		st.Groups[m.Name] = &ConsumerGroup{LastDelivered: m.Start, Pending: make(map[string]*PendingEntry)}
+		return sizeOfGroup(m.Name), nil
+	})
+}
+
+// delivers entries the group has not seen yet to the consumer, they stay pending until XAck,
+// waits up to block for new entries if there are none, a done ctx stops waiting
+func (s *Store) XReadGroup(ctx context.Context, key, group, consumer string, count int, block time.Duration) ([]StreamEntry, error) {
+	result := []StreamEntry{}
+	err := s.block(ctx, key, block, func() (bool, error) {
+		m := &streamDeliver{Group: group, Consumer: consumer, count: count}
+		err := s.mutate(key, nil, m)
+		if err == errNothingToDeliver {
+			return false, nil
+		}
+		result = m.entries
+		return err == nil, err
+	})
+	return result, err
+}
+
+// delivered entries and the time come from the state at the moment, they are replayed as they were resolved
+type streamDeliver struct {
+	Group     string
+	Consumer  string
+	IDs       []StreamID
+	Delivered time.Time
+	count     int
+	entries   []StreamEntry
+}
+
+func (m *streamDeliver) apply(key string, val interface{}) (int64, error) {
+	return changeStream(key, val, func(st *Stream) (int64, error) {
+		g, ok := st.Groups[m.Group]
+		if !ok {
+			return 0, fmt.Errorf(errGroupNotFoundFmt, m.Group)
+		}
+		m.entries = take(st.Entries[st.after(g.LastDelivered):], m.count)
+		if len(m.entries) == 0 {
+			return 0, errNothingToDeliver // nothing is changed, nothing goes to the log
+		}
+		for _, e := range m.entries {
+			m.IDs = append(m.IDs, e.ID)
+		}
+		m.Delivered = time.Now()
+		return st.deliver(g, m.Consumer, m.IDs, m.Delivered), nil
+	})
+}
+
+func (m *streamDeliver) replay(val interface{}) {
+	if st, ok := val.(*Stream); ok && st.Groups[m.Group] != nil {
+		st.deliver(st.Groups[m.Group], m.Consumer, m.IDs, m.Delivered)
+	}
+}
+
+// removes entries from the pending list of the group, returns how many of them were pending
+func (s *Store) XAck(key, group string, ids ...StreamID) (int, error) {
+	m := &streamAck{Group: group, IDs: ids}
+	err := s.mutate(key, nil, m)
+	return m.acked, err
+}
+
+type streamAck struct {
+	Group string
+	IDs   []StreamID
+	acked int
+}
+
+func (m *streamAck) apply(key string, val interface{}) (int64, error) {
+	return changeStream(key, val, func(st *Stream) (int64, error) {
+		g, ok := st.Groups[m.Group]
+		if !ok {
+			return 0, fmt.Errorf(errGroupNotFoundFmt, m.Group)
+		}
+		var delta int64
+		for _, id := range m.IDs {
+			if p, ok := g.Pending[id.String()]; ok {
+				delete(g.Pending, id.String())
+				delta -= sizeOfPending(p)
+				m.acked++
+			}
+		}
+		return delta, nil
+	})
+}
+
+// entries delivered to consumers of the group and not acked yet, ordered by id
+func (s *Store) XPending(key, group string) ([]PendingEntry, error) {
+	result := []PendingEntry{}
+	err := s.read(key, func(val interface{}) error {
+		if val == nil {
+			return fmt.Errorf(errKeyNotFoundFmt, key)
+		}
+		st, ok := val.(*Stream)
+		if !ok {
+			return fmt.Errorf(errWrongTypeFmt, key)
+		}
+		g, ok := st.Groups[group]
+		if !ok {
+			return fmt.Errorf(errGroupNotFoundFmt, group)
+		}
+		for _, p := range g.Pending {
+			result = append(result, *p)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].ID.Less(result[j].ID)
+		})
+		return nil
+	})
+	return result, err
+}
+
+// missing key is an empty stream
+func asStream(key string, val interface{}) (*Stream, error) {
+	if val == nil {
+		return newStream().(*Stream), nil
+	}
+	st, ok := val.(*Stream)
+	if !ok {
+		return nil, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	return st, nil
+}
+
+func sizeOfEntry(e StreamEntry) int64 {
+	size := int64(streamEntryOverhead)
+	for f, v := range e.Fields {
+		size += sizeOfField(f, v)
+	}
+	return size
+}
+
+func sizeOfPending(p *PendingEntry) int64 {
+	return pendingEntryOverhead + int64(len(p.Consumer))
+}
+
+func sizeOfGroup(name string) int64 {
+	return consumerGroupOverhead + int64(len(name))
+}
@@ -0,0 +1,242 @@
+package store_test
+
+import (
+	"context"
+	"github.com/baratov/golang-playground/store"
+	"testing"
+	"time"
+)
+
+func TestXAddXRange(t *testing.T) {
+	s := newStore(t)
+
+	var ids []store.StreamID
+	for n := 0; n < 5; n++ {
+		id, err := s.XAdd("events", map[string]interface{}{"n": n}, store.NoMaxLen)
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if len(ids) > 0 && !ids[len(ids)-1].Less(id) {
+			t.Errorf("Expected %v to be greater than %v", id, ids[len(ids)-1])
+		}
+		ids = append(ids, id)
+	}
+
+	entries, err := s.XRange("events", ids[1], ids[3], 0)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if len(entries) != 3 || entries[0].ID != ids[1] || entries[2].ID != ids[3] {
+		t.Errorf("Expected entries from %v to %v, but found %v", ids[1], ids[3], entries)
+	}
+
+	if entries, _ = s.XRange("events", store.StreamID{}, store.LastStreamID, 2); len(entries) != 2 || entries[0].Fields["n"] != 0 {
+		t.Errorf("Expected 2 first entries, but found %v", entries)
+	}
+	if n, _ := s.XLen("events"); n != 5 {
+		t.Errorf("Expected 5 entries, but found %v", n)
+	}
+}
+
+func TestXTrim(t *testing.T) {
+	s := newStore(t)
+	for n := 0; n < 5; n++ {
+		s.XAdd("events", map[string]interface{}{"n": n}, 3)
+	}
+	if n, _ := s.XLen("events"); n != 3 {
+		t.Errorf("Expected 3 entries, but found %v", n)
+	}
+
+	removed, err := s.XTrim("events", 1)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 removed entries, but found %v", removed)
+	}
+	entries, _ := s.XRange("events", store.StreamID{}, store.LastStreamID, 0)
+	if len(entries) != 1 || entries[0].Fields["n"] != 4 {
+		t.Errorf("Expected the last entry, but found %v", entries)
+	}
+
+	if _, err = s.XTrim("events", -1); err == nil {
+		t.Errorf("Expected error for negative maxlen")
+	}
+	if _, err = s.XAdd("events", map[string]interface{}{"n": 5}, -1); err == nil {
+		t.Errorf("Expected error for negative maxlen")
+	}
+	if n, _ := s.XLen("events"); n != 1 {
+		t.Errorf("Expected 1 entry, but found %v", n)
+	}
+}
+
+func TestXRead_Blocking(t *testing.T) {
+	s := newStore(t)
+	first, _ := s.XAdd("events", map[string]interface{}{"n": 1}, store.NoMaxLen)
+
+	entries, err := s.XRead(context.Background(), "events", store.StreamID{}, 0, 0)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if len(entries) != 1 || entries[0].ID != first {
+		t.Errorf("Expected the first entry, but found %v", entries)
+	}
+
+	// nothing new, times out
+	start := time.Now()
+	if entries, _ = s.XRead(context.Background(), "events", store.LastStreamID, 0, time.Millisecond*50); len(entries) != 0 {
+		t.Errorf("Expected no entries, but found %v", entries)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*50 {
+		t.Errorf("Expected to wait for 50ms, but waited %v", elapsed)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		s.XAdd("events", map[string]interface{}{"n": 2}, store.NoMaxLen)
+	}()
+	start = time.Now()
+	entries, err = s.XRead(context.Background(), "events", store.LastStreamID, 0, time.Second)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Errorf("Expected to wake up on the new entry, but waited %v", elapsed)
+	}
+	if len(entries) != 1 || entries[0].Fields["n"] != 2 {
+		t.Errorf("Expected the second entry, but found %v", entries)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		cancel()
+	}()
+	start = time.Now()
+	if entries, _ = s.XRead(ctx, "events", store.LastStreamID, 0, time.Minute); len(entries) != 0 {
+		t.Errorf("Expected no entries, but found %v", entries)
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*500 {
+		t.Errorf("Expected to stop waiting once the context is done, but waited %v", elapsed)
+	}
+}
+
+func TestConsumerGroup(t *testing.T) {
+	s := newStore(t)
+	s.XAdd("events", map[string]interface{}{"n": 0}, store.NoMaxLen)
+	if err := s.XGroupCreate("events", "workers", store.LastStreamID); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	err := s.XGroupCreate("events", "workers", store.StreamID{})
+	expected := "group 'workers' already exists"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+
+	for n := 1; n <= 3; n++ {
+		s.XAdd("events", map[string]interface{}{"n": n}, store.NoMaxLen)
+	}
+
+	// every entry goes to one consumer only
+	first, err := s.XReadGroup(context.Background(), "events", "workers", "alice", 2, 0)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	second, _ := s.XReadGroup(context.Background(), "events", "workers", "bob", 0, 0)
+	if len(first) != 2 || len(second) != 1 || first[0].Fields["n"] != 1 || second[0].Fields["n"] != 3 {
+		t.Errorf("Expected entries 1, 2 for alice and 3 for bob, but found %v and %v", first, second)
+	}
+	if entries, _ := s.XReadGroup(context.Background(), "events", "workers", "bob", 0, 0); len(entries) != 0 {
+		t.Errorf("Expected nothing left to deliver, but found %v", entries)
+	}
+
+	acked, err := s.XAck("events", "workers", first[0].ID, first[0].ID)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if acked != 1 {
+		t.Errorf("Expected 1 acked entry, but found %v", acked)
+	}
+	pending, err := s.XPending("events", "workers")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if len(pending) != 2 || pending[0].ID != first[1].ID || pending[0].Consumer != "alice" || pending[1].Consumer != "bob" {
+		t.Errorf("Expected entry 2 pending for alice and 3 for bob, but found %+v", pending)
+	}
+
+	_, err = s.XReadGroup(context.Background(), "events", "nonExisting", "alice", 0, 0)
+	expected = "group 'nonExisting' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestXReadGroup_Blocking(t *testing.T) {
+	s := newStore(t)
+	s.XGroupCreate("events", "workers", store.LastStreamID)
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		s.XAdd("events", map[string]interface{}{"n": 1}, store.NoMaxLen)
+	}()
+	entries, err := s.XReadGroup(context.Background(), "events", "workers", "alice", 0, time.Second)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 entry, but found %v", entries)
+	}
+}
+
+func TestParseStreamID(t *testing.T) {
+	for s, expected := range map[string]store.StreamID{
+		"-":     {},
+		"+":     store.LastStreamID,
+		"$":     store.LastStreamID,
+		"12":    {Ms: 12},
+		"12-34": {Ms: 12, Seq: 34},
+	} {
+		id, err := store.ParseStreamID(s)
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if id != expected {
+			t.Errorf("Expected id of %s is %v, but found %v", s, expected, id)
+		}
+	}
+	if _, err := store.ParseStreamID("12-x"); err == nil {
+		t.Errorf("Expected error for malformed id")
+	}
+}
+
+func TestStream_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.XAdd("events", map[string]interface{}{"n": 1}, store.NoMaxLen)
+	s.XGroupCreate("events", "workers", store.StreamID{})
+	s.XReadGroup(context.Background(), "events", "workers", "alice", 0, 0)
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.XAdd("events", map[string]interface{}{"n": 2}, store.NoMaxLen)
+	if n, _ := r.XLen("events"); n != 2 {
+		t.Errorf("Expected 2 entries, but found %v", n)
+	}
+	if pending, _ := r.XPending("events", "workers"); len(pending) != 1 || pending[0].Consumer != "alice" {
+		t.Errorf("Expected 1 entry pending for alice, but found %+v", pending)
+	}
+	entries, _ := r.XReadGroup(context.Background(), "events", "workers", "bob", 0, 0)
+	if len(entries) != 1 || entries[0].Fields["n"] != 2 {
+		t.Errorf("Expected the second entry for bob, but found %v", entries)
+	}
+
+	//teardown
+	r.Stop()
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"container/heap"
+	"time"
+)
+
+// returns remaining time to live of the key, NoExpiration for persistent keys
+func (s *Store) TTL(key string) (time.Duration, error) {
+	sh := s.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	i, err := sh.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	if i.Expiration.IsZero() {
+		return NoExpiration, nil
+	}
+	return time.Until(i.Expiration), nil
+}
+
+func (s *Store) Expire(key string, ttl time.Duration) error {
+	return s.ExpireAt(key, time.Now().Add(ttl))
+}
+
+// the key is deleted right away if the time is in the past
+func (s *Store) ExpireAt(key string, t time.Time) error {
+	return s.setExpiration(key, t)
+}
+
+// removes expiration of the key
+func (s *Store) Persist(key string) error {
+	return s.setExpiration(key, time.Time{})
+}
+
+func (s *Store) setExpiration(key string, t time.Time) error {
+	sh := s.shard(key)
+	err := sh.locked(func() error {
+		i, err := sh.lookup(key)
+		if err != nil {
+			return err
+		}
+		if !t.IsZero() && !t.After(time.Now()) {
+			sh.delete(key)
+			s.wal.append(opDelete, key, item{})
+			return nil
+		}
+		sh.setExpiration(i, t)
+		old := i.Version
+		i.Version = sh.nextVersion()
+		sh.emit(EventUpdate, key, old, i, nil)
+		s.wal.append(opSet, key, *i)
+		return nil
+	})
+
+	if err == nil {
+		s.updated()
+	}
+	return err
+}
+
+// moves the item within expiration heap, should be called under write lock
+func (sh *shard) setExpiration(i *item, t time.Time) {
+	i.Expiration = t
+	switch {
+	case i.index >= 0 && t.IsZero():
+		heap.Remove(&sh.expiring, i.index)
+	case i.index >= 0:
+		heap.Fix(&sh.expiring, i.index)
+	case !t.IsZero():
+		heap.Push(&sh.expiring, i)
+	}
+}
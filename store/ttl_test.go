@@ -0,0 +1,124 @@
+package store_test
+
+import (
+	"github.com/baratov/golang-playground/store"
+	"testing"
+	"time"
+)
+
+func TestSet_NoExpiration(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, store.NoExpiration)
+	time.Sleep(time.Millisecond * 1100) // let the expiration tick pass
+
+	val, err := s.Get("someKey")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if val != 123 {
+		t.Errorf("Expected value is 123, but found %v", val)
+	}
+
+	ttl, err := s.TTL("someKey")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if ttl != store.NoExpiration {
+		t.Errorf("Expected ttl is %v, but found %v", store.NoExpiration, ttl)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Minute)
+
+	ttl, err := s.TTL("someKey")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected ttl is in (0, %v], but found %v", time.Minute, ttl)
+	}
+}
+
+func TestTTL_NonExistingKey(t *testing.T) {
+	s := newStore(t)
+
+	_, err := s.TTL("someKey")
+	expected := "key 'someKey' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, store.NoExpiration)
+
+	if err := s.Expire("someKey", time.Millisecond*100); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	time.Sleep(time.Millisecond * 200)
+
+	_, err := s.Get("someKey")
+	expected := "key 'someKey' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestExpireAt_Past(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Minute)
+
+	if err := s.ExpireAt("someKey", time.Now().Add(-time.Second)); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if keys := s.Keys(); len(keys) != 0 {
+		t.Errorf("Expected no keys, but found %v", keys)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Second)
+
+	if err := s.Persist("someKey"); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	time.Sleep(time.Millisecond * 2100)
+
+	val, err := s.Get("someKey")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if val != 123 {
+		t.Errorf("Expected value is 123, but found %v", val)
+	}
+}
+
+func TestDefaultTTL(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithDefaultTTL(time.Minute),
+	)
+	defer s.Stop()
+	s.Set("someKey", 123, store.NoExpiration)
+	s.Set("otherKey", 123, time.Hour)
+	s.LPush("someList", "a")
+
+	for key, expected := range map[string]time.Duration{"someKey": time.Minute, "otherKey": time.Hour, "someList": time.Minute} {
+		ttl, err := s.TTL(key)
+		if err != nil {
+			t.Errorf("Error found %s", err.Error())
+		}
+		if ttl <= expected-time.Second || ttl > expected {
+			t.Errorf("Expected ttl of %v is about %v, but found %v", key, expected, ttl)
+		}
+	}
+
+	s.Persist("someKey")
+	if ttl, _ := s.TTL("someKey"); ttl != store.NoExpiration {
+		t.Errorf("Expected no expiration, but found %v", ttl)
+	}
+}
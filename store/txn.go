@@ -0,0 +1,139 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// MULTI/EXEC alike transactions: all shards are locked for the time of the function,
+// writes are buffered in Tx and applied only if the function returns no error
+
+type Tx struct {
+	s      *Store
+	writes map[string]*item  // nil stands for deleted key
+	values map[string][]byte // encoded values of writes
+	order  []string          // keys in order of the first write, to keep the log deterministic
+	err    error             // of a value which can't go to the log, fails the transaction
+}
+
+// runs fn under lock of the whole store, fn must not call methods of the store itself
+func (s *Store) Txn(fn func(tx *Tx) error) error {
+	tx := &Tx{
+		s:      s,
+		writes: make(map[string]*item),
+		values: make(map[string][]byte),
+	}
+
+	err := s.lockedAll(func() error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if tx.err != nil {
+			return tx.err
+		}
+		tx.commit()
+		return nil
+	})
+
+	if err == nil && len(tx.order) > 0 {
+		s.evict()
+		s.updated()
+	}
+	return err
+}
+
+// locks shards in order and releases them in reverse, even if fn panics
+func (s *Store) lockedAll(fn func() error) error {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+	}
+	defer func() {
+		for n := len(s.shards) - 1; n >= 0; n-- {
+			s.shards[n].mu.Unlock()
+		}
+	}()
+	return fn()
+}
+
+func (tx *Tx) Get(key string) (interface{}, error) {
+	i, err := tx.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return i.value(), nil
+}
+
+// a value gob doesn't know fails the transaction
+func (tx *Tx) Set(key string, value interface{}, ttl time.Duration) {
+	encoded, err := encodeValue(value)
+	if err != nil && tx.err == nil {
+		tx.err = err
+	}
+	tx.values[key] = encoded
+	tx.write(key, &item{
+		Value:      value,
+		Expiration: tx.s.expiration(ttl),
+	})
+}
+
+func (tx *Tx) Update(key string, value interface{}, ttl time.Duration) error {
+	if _, err := tx.lookup(key); err != nil {
+		return err
+	}
+	tx.Set(key, value, ttl)
+	return nil
+}
+
+func (tx *Tx) Delete(key string) {
+	tx.write(key, nil)
+}
+
+// fails unless the key had the expected version when the transaction started,
+// 0 stands for a key which doesn't exist
+func (tx *Tx) Check(key string, version uint64) error {
+	var current uint64
+	if i, err := tx.s.shard(key).lookup(key); err == nil {
+		current = i.Version
+	}
+	if current != version {
+		return fmt.Errorf("%w for key '%v'", ErrVersionMismatch, key)
+	}
+	return nil
+}
+
+// sees writes of the transaction itself
+func (tx *Tx) lookup(key string) (*item, error) {
+	if i, written := tx.writes[key]; written {
+		if i == nil || i.isExpired() {
+			return nil, fmt.Errorf(errKeyNotFoundFmt, key)
+		}
+		return i, nil
+	}
+	return tx.s.shard(key).lookup(key)
+}
+
+func (tx *Tx) write(key string, i *item) {
+	if _, written := tx.writes[key]; !written {
+		tx.order = append(tx.order, key)
+	}
+	tx.writes[key] = i
+}
+
+func (tx *Tx) commit() {
+	if len(tx.order) == 0 {
+		return
+	}
+
+	batch := make([]walRecord, 0, len(tx.order))
+	for _, key := range tx.order {
+		sh := tx.s.shard(key)
+		if i := tx.writes[key]; i != nil {
+			sh.set(key, i)
+			batch = append(batch, encodedSet(key, *i, tx.values[key]))
+		} else {
+			sh.delete(key)
+			batch = append(batch, walRecord{Op: opDelete, Key: key})
+		}
+	}
+	tx.s.wal.appendBatch(batch)
+}
@@ -0,0 +1,144 @@
+package store_test
+
+import (
+	"errors"
+	"github.com/baratov/golang-playground/store"
+	"testing"
+	"time"
+)
+
+func TestTxn(t *testing.T) {
+	s := newStore(t)
+	s.Set("key1", 1, time.Minute)
+	s.Set("key2", 2, time.Minute)
+
+	err := s.Txn(func(tx *store.Tx) error {
+		val, err := tx.Get("key1")
+		if err != nil {
+			return err
+		}
+		tx.Set("key3", val.(int)+2, time.Minute)
+		tx.Delete("key2")
+		return tx.Update("key1", 4, time.Minute)
+	})
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+
+	for key, expected := range map[string]interface{}{"key1": 4, "key2": nil, "key3": 3} {
+		if val, _ := s.Get(key); val != expected {
+			t.Errorf("Expected value of %s is %v, but found %v", key, expected, val)
+		}
+	}
+}
+
+func TestTxn_ReadsOwnWrites(t *testing.T) {
+	s := newStore(t)
+
+	s.Txn(func(tx *store.Tx) error {
+		tx.Set("someKey", 123, time.Minute)
+		if val, _ := tx.Get("someKey"); val != 123 {
+			t.Errorf("Expected value is 123, but found %v", val)
+		}
+		tx.Delete("someKey")
+		if _, err := tx.Get("someKey"); err == nil {
+			t.Errorf("Expected deleted key not to be found")
+		}
+		return nil
+	})
+}
+
+func TestTxn_Rollback(t *testing.T) {
+	s := newStore(t)
+	s.Set("key1", 1, time.Minute)
+
+	err := s.Txn(func(tx *store.Tx) error {
+		tx.Set("key1", 2, time.Minute)
+		tx.Set("key2", 2, time.Minute)
+		return tx.Update("nonExisting", 3, time.Minute)
+	})
+	expected := "key 'nonExisting' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+
+	if val, _ := s.Get("key1"); val != 1 {
+		t.Errorf("Expected value is 1, but found %v", val)
+	}
+	if _, err := s.Get("key2"); err == nil {
+		t.Errorf("Expected key2 not to be written")
+	}
+}
+
+// a panic in the function must not leave the shards locked
+func TestTxn_Panic(t *testing.T) {
+	s := newStore(t)
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("Expected panic of the function")
+			}
+		}()
+		s.Txn(func(tx *store.Tx) error {
+			tx.Set("someKey", 1, time.Minute)
+			panic("some panic")
+		})
+	}()
+
+	s.Set("otherKey", 2, time.Minute) // blocks forever on a locked shard
+	if _, err := s.Get("someKey"); err == nil {
+		t.Errorf("Expected someKey not to be written")
+	}
+}
+
+func TestTxn_Check(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Minute)
+	_, version, _ := s.GetWithVersion("someKey")
+	s.Update("someKey", 234, time.Minute)
+
+	err := s.Txn(func(tx *store.Tx) error {
+		if err := tx.Check("someKey", version); err != nil {
+			return err
+		}
+		tx.Set("someKey", 345, time.Minute)
+		return nil
+	})
+	if !errors.Is(err, store.ErrVersionMismatch) {
+		t.Errorf("Expected error to be ErrVersionMismatch, but found %v", err)
+	}
+	if val, _ := s.Get("someKey"); val != 234 {
+		t.Errorf("Expected value is 234, but found %v", val)
+	}
+}
+
+func TestTxn_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+		store.WithFsyncPolicy(store.FsyncAlways),
+	)
+	s.Set("key1", 1, time.Minute)
+	s.Txn(func(tx *store.Tx) error {
+		tx.Delete("key1")
+		tx.Set("key2", 2, time.Minute)
+		return nil
+	})
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	if _, err := r.Get("key1"); err == nil {
+		t.Errorf("Expected key1 to be deleted")
+	}
+	if val, _ := r.Get("key2"); val != 2 {
+		t.Errorf("Expected value is 2, but found %v", val)
+	}
+
+	//teardown
+	s.Stop()
+	r.Stop()
+}
@@ -0,0 +1,121 @@
+package store
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Typed is a view of the store with values of a single type, so callers don't assert types,
+// T should be registered before New restores values of it from the log and snapshots
+//	store.Register[User]()
+//	s := store.New(store.WithRestoreFromFile(filename))
+//	users := store.NewTyped[User](s)
+//	users.Set("alice", User{Name: "Alice"}, time.Hour)
+//	u, err := users.Get("alice")
+
+type Typed[T any] struct {
+	s *Store
+}
+
+// returned by Typed when the value of the key is of another type
+type WrongTypeError struct {
+	Key      string
+	Expected string
+	Found    string
+}
+
+func (e *WrongTypeError) Error() string {
+	return fmt.Sprintf(errWrongTypeFmt+": expected %v, found %v", e.Key, e.Expected, e.Found)
+}
+
+// registers T in gob, so values of T can be written to the log and snapshots and read back,
+// restore decodes them inside New, so it should be called before New, gob keeps a single name
+// for a type and pointers to it, so the type itself is registered for pointers
+func Register[T any]() {
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Interface {
+		gob.Register(reflect.Zero(rt).Interface())
+	}
+}
+
+// registers T as Register does, which is too late for values restored by s
+func NewTyped[T any](s *Store) *Typed[T] {
+	Register[T]()
+	return &Typed[T]{s: s}
+}
+
+// values set over http are decoded from json as maps, slices and float64,
+// they are converted to T through json, a pointer T points to a copy of the stored value
+func (t *Typed[T]) Get(key string) (T, error) {
+	var result T
+	val, err := t.s.Get(key)
+	if err != nil {
+		return result, err
+	}
+	if v, ok := val.(T); ok {
+		return copyPointer(v), nil
+	}
+	if v, ok := pointerOrValue[T](val); ok {
+		return v, nil
+	}
+	if fromJSON(val) {
+		if b, err := json.Marshal(val); err == nil && json.Unmarshal(b, &result) == nil {
+			return result, nil
+		}
+	}
+	return result, &WrongTypeError{
+		Key:      key,
+		Expected: reflect.TypeOf(&result).Elem().String(),
+		Found:    fmt.Sprintf("%T", val),
+	}
+}
+
+func (t *Typed[T]) Set(key string, value T, ttl time.Duration) {
+	t.s.Set(key, value, ttl)
+}
+
+func (t *Typed[T]) Update(key string, value T, ttl time.Duration) error {
+	return t.s.Update(key, value, ttl)
+}
+
+// values restored from gob lose or gain a pointer
+func pointerOrValue[T any](val interface{}) (T, bool) {
+	var result T
+	rt, rv := reflect.TypeOf(&result).Elem(), reflect.ValueOf(val)
+	switch {
+	case !rv.IsValid():
+	case rt.Kind() == reflect.Ptr && rv.Type() == rt.Elem():
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		return p.Interface().(T), true
+	case rv.Kind() == reflect.Ptr && rv.Type().Elem() == rt && !rv.IsNil():
+		return rv.Elem().Interface().(T), true
+	}
+	return result, false
+}
+
+// the value a pointer points to is copied, so changes of the caller don't reach the store,
+// the copy is shallow, as values of other types handed out by Get
+func copyPointer[T any](v T) T {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return v
+	}
+	p := reflect.New(rv.Type().Elem())
+	p.Elem().Set(rv.Elem())
+	return p.Interface().(T)
+}
+
+func fromJSON(val interface{}) bool {
+	switch val.(type) {
+	case map[string]interface{}, []interface{}, float64:
+		return true
+	}
+	return false
+}
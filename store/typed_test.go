@@ -0,0 +1,140 @@
+package store_test
+
+import (
+	"errors"
+	"github.com/baratov/golang-playground/store"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+type user struct {
+	Name  string
+	Age   int
+	Roles []string
+}
+
+func TestTyped(t *testing.T) {
+	s := newStore(t)
+	users := store.NewTyped[user](s)
+
+	users.Set("alice", user{Name: "Alice", Age: 30}, time.Minute)
+	u, err := users.Get("alice")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if u.Name != "Alice" || u.Age != 30 {
+		t.Errorf("Expected Alice of 30, but found %v", u)
+	}
+
+	if err = users.Update("alice", user{Name: "Alice", Age: 31}, time.Minute); err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if u, _ = users.Get("alice"); u.Age != 31 {
+		t.Errorf("Expected age 31, but found %v", u.Age)
+	}
+	if err = users.Update("bob", user{Name: "Bob"}, time.Minute); err == nil {
+		t.Errorf("Expected error for a missing key")
+	}
+
+	pointers := store.NewTyped[*user](s)
+	pointers.Set("bob", &user{Name: "Bob"}, time.Minute)
+	p, _ := pointers.Get("bob")
+	p.Name = "Robert"
+	if p, _ = pointers.Get("bob"); p.Name != "Bob" {
+		t.Errorf("Expected a copy of the stored value, but found %v", p.Name)
+	}
+
+	_, err = users.Get("nonExisting")
+	expected := "key 'nonExisting' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestTyped_WrongType(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", "some_string_value", time.Minute)
+
+	_, err := store.NewTyped[int](s).Get("someKey")
+	var wrongType *store.WrongTypeError
+	if !errors.As(err, &wrongType) {
+		t.Errorf("Expected WrongTypeError, but found %v", err)
+	}
+	expected := "wrong type for key 'someKey': expected int, found string"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+// values set over http are decoded from json
+func TestTyped_FromJSON(t *testing.T) {
+	s := newStore(t)
+	s.Set("alice", map[string]interface{}{"Name": "Alice", "Age": float64(30), "Roles": []interface{}{"admin"}}, time.Minute)
+	s.Set("count", float64(5), time.Minute)
+
+	u, err := store.NewTyped[user](s).Get("alice")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if u.Name != "Alice" || u.Age != 30 || len(u.Roles) != 1 || u.Roles[0] != "admin" {
+		t.Errorf("Expected Alice of 30 with admin role, but found %v", u)
+	}
+	if n, _ := store.NewTyped[int](s).Get("count"); n != 5 {
+		t.Errorf("Expected 5, but found %v", n)
+	}
+
+	s.Set("ratio", 1.5, time.Minute)
+	if _, err = store.NewTyped[int](s).Get("ratio"); err == nil {
+		t.Errorf("Expected error for 1.5 as int")
+	}
+}
+
+// values of the type are restored in a new process, where only Register knows it before New
+type account struct {
+	Name  string
+	Roles []string
+}
+
+func TestTyped_Restore(t *testing.T) {
+	if filename := os.Getenv("TYPED_RESTORE_FILE"); filename != "" {
+		restoreAccounts(t, filename)
+		return
+	}
+
+	filename := tempFilename(t)
+	store.Register[account]()
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.Set("alice", account{Name: "Alice", Roles: []string{"admin"}}, time.Minute)
+	s.Set("bob", &account{Name: "Bob"}, time.Minute)
+	s.Stop()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestTyped_Restore$", "-test.v")
+	cmd.Env = append(os.Environ(), "TYPED_RESTORE_FILE="+filename)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Expected restore in a new process, but found %v:\n%s", err, out)
+	}
+}
+
+func restoreAccounts(t *testing.T, filename string) {
+	store.Register[account]()
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	defer r.Stop()
+
+	a, err := store.NewTyped[account](r).Get("alice")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if a.Name != "Alice" || len(a.Roles) != 1 {
+		t.Errorf("Expected Alice with a role, but found %v", a)
+	}
+	if p, err := store.NewTyped[*account](r).Get("bob"); err != nil || p.Name != "Bob" {
+		t.Errorf("Expected Bob, but found %v, %v", p, err)
+	}
+}
@@ -0,0 +1,307 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// append-only log of mutations, replayed on top of the last snapshot
+// sets and deletes hold the resulting item, so replaying them twice is harmless,
+// changes of containers in place hold the mutation along with the version it produced
+// and are skipped for items which have this version already
+
+type FsyncPolicy int
+
+const (
+	FsyncEverySecond FsyncPolicy = iota // default, at most one second of writes is lost on power failure
+	FsyncAlways                         // fsync after every record, slow but nothing is lost
+	FsyncNever                          // leave it to the OS
+)
+
+const (
+	opSet byte = iota
+	opDelete
+	opBatch  // records applied all together or not at all
+	opChange // mutation of a container in place
+
+	walSuffix       = ".wal."
+	walSyncInterval = time.Second
+)
+
+type walRecord struct {
+	Op     byte
+	Key    string
+	Item   item
+	Batch  []walRecord
+	Change mutation
+	Value  []byte // of a set, encoded by encodeValue before the lock, Item goes without the value then
+}
+
+type encodedValue struct {
+	Value interface{}
+}
+
+// every segment is written by a single gob.Encoder, a new segment is started on each rotation
+type wal struct {
+	mu       sync.Mutex
+	filename string
+	seq      int
+	file     *os.File
+	encoder  *gob.Encoder
+	policy   FsyncPolicy
+	dirty    bool
+}
+
+func openWAL(filename string, policy FsyncPolicy) *wal {
+	w := &wal{
+		filename: filename,
+		policy:   policy,
+	}
+	if seqs := segments(filename); len(seqs) > 0 {
+		w.seq = seqs[len(seqs)-1]
+	}
+	w.openSegment()
+	return w
+}
+
+func (w *wal) openSegment() {
+	for {
+		w.seq++
+		file, err := os.OpenFile(segmentName(w.filename, w.seq), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue // somebody else writes to the same filename, skip his segment
+		}
+		if err != nil {
+			panic(err)
+		}
+		w.file = file
+		w.encoder = gob.NewEncoder(file)
+		return
+	}
+}
+
+// gob fails on types which were not registered, so values are encoded before they get under lock
+// and a failed write leaves neither a changed item nor a broken log behind, the log takes the result
+// as it is and doesn't encode the value again
+func encodeValue(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(encodedValue{Value: value}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeValue(b []byte) (interface{}, error) {
+	var v encodedValue
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v)
+	return v.Value, err
+}
+
+// mutations are registered in files of their types, those which carry values are checked as values are
+func encodableChange(m mutation) error {
+	return gob.NewEncoder(io.Discard).Encode(walRecord{Change: m})
+}
+
+func (w *wal) append(op byte, key string, i item) {
+	w.write(walRecord{Op: op, Key: key, Item: i})
+}
+
+// value is the result of encodeValue for the value of i
+func (w *wal) appendEncoded(key string, i item, value []byte) {
+	w.write(encodedSet(key, i, value))
+}
+
+func encodedSet(key string, i item, value []byte) walRecord {
+	return walRecord{Op: opSet, Key: key, Item: item{Expiration: i.Expiration, Version: i.Version}, Value: value}
+}
+
+// the value stays out of the record, the version tells replay whether the change is in the snapshot already
+func (w *wal) appendChange(key string, i item, m mutation) {
+	w.write(walRecord{Op: opChange, Key: key, Item: item{Expiration: i.Expiration, Version: i.Version}, Change: m})
+}
+
+// a batch is a single record, so a torn tail drops it completely
+func (w *wal) appendBatch(batch []walRecord) {
+	w.write(walRecord{Op: opBatch, Batch: batch})
+}
+
+func (w *wal) write(r walRecord) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil { // closed by Stop
+		return
+	}
+	if err := w.encoder.Encode(r); err != nil {
+		panic(err)
+	}
+	if w.policy == FsyncAlways {
+		if err := w.file.Sync(); err != nil {
+			panic(err)
+		}
+	} else if w.policy == FsyncEverySecond {
+		w.dirty = true
+	}
+}
+
+func (w *wal) sync() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || !w.dirty {
+		return
+	}
+	if err := w.file.Sync(); err != nil {
+		panic(err)
+	}
+	w.dirty = false
+}
+
+// starts a new segment and returns the sequence of the last closed one,
+// everything up to it is covered by a snapshot taken after the rotation
+func (w *wal) rotate() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	covered := w.seq
+	w.closeSegment()
+	w.openSegment()
+	return covered
+}
+
+// removes segments covered by a snapshot, oldest first,
+// so a crash in the middle leaves a suffix of the log which is still safe to replay
+func (w *wal) truncate(upTo int) {
+	for _, seq := range segments(w.filename) {
+		if seq > upTo {
+			return
+		}
+		if err := os.Remove(segmentName(w.filename, seq)); err != nil && !os.IsNotExist(err) {
+			panic(err)
+		}
+	}
+}
+
+func (w *wal) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closeSegment()
+}
+
+func (w *wal) closeSegment() {
+	if w.file == nil {
+		return
+	}
+	if w.policy != FsyncNever {
+		if err := w.file.Sync(); err != nil {
+			panic(err)
+		}
+	}
+	if err := w.file.Close(); err != nil {
+		panic(err)
+	}
+	w.file = nil
+	w.encoder = nil
+	w.dirty = false
+}
+
+// applies all segments of the filename to items in the order they were written
+func replay(filename string, items map[string]*item) {
+	for _, seq := range segments(filename) {
+		replaySegment(segmentName(filename, seq), items)
+	}
+}
+
+func replaySegment(name string, items map[string]*item) {
+	file, err := os.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(file)
+	for {
+		var r walRecord
+		err := decoder.Decode(&r)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return // torn tail after a crash, the record was never acknowledged
+		}
+		if err != nil {
+			panic(fmt.Errorf("replay %s: %v", name, err))
+		}
+
+		apply(r, items)
+	}
+}
+
+func apply(r walRecord, items map[string]*item) {
+	switch r.Op {
+	case opSet:
+		i := r.Item
+		if r.Value != nil {
+			val, err := decodeValue(r.Value)
+			if err != nil {
+				panic(fmt.Errorf("replay of key '%v': %v", r.Key, err))
+			}
+			i.Value = val
+		}
+		items[r.Key] = &i
+	case opDelete:
+		delete(items, r.Key)
+	case opBatch:
+		for _, r := range r.Batch {
+			apply(r, items)
+		}
+	case opChange:
+		i, ok := items[r.Key]
+		if !ok || i.Version >= r.Item.Version {
+			return // deleted later on or changed in the snapshot already
+		}
+		if rp, ok := r.Change.(replayer); ok {
+			rp.replay(i.Value)
+		} else {
+			r.Change.apply(r.Key, i.Value)
+		}
+		if rp, ok := r.Change.(replacer); ok {
+			i.Value = rp.replaced()
+		}
+		i.Version = r.Item.Version
+		if c, ok := i.Value.(container); ok && c.len() == 0 {
+			delete(items, r.Key)
+		}
+	}
+}
+
+func segmentName(filename string, seq int) string {
+	return fmt.Sprintf("%s%s%06d", filename, walSuffix, seq)
+}
+
+// returns sequences of existing segments in ascending order
+func segments(filename string) []int {
+	names, err := filepath.Glob(filename + walSuffix + "*")
+	if err != nil {
+		panic(err)
+	}
+
+	prefix := filepath.Base(filename) + walSuffix // Glob cleans the paths it returns
+	seqs := make([]int, 0, len(names))
+	for _, name := range names {
+		seq, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(name), prefix))
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	return seqs
+}
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// changes of keys are sent to watchers of their prefixes right under the lock of the shard,
+// so events of a key come in order of its versions, sends never block: a watcher which doesn't
+// keep up and fills its buffer is closed with ErrWatchOverflow, it missed events and should
+// read the keys again before watching further, or resume from the history of the store if it has one
+
+type EventType string
+
+const (
+	EventSet    EventType = "set" // of a key which didn't exist
+	EventUpdate EventType = "update"
+	EventDelete EventType = "delete"
+	EventExpire EventType = "expire"
+	EventEvict  EventType = "evict"
+
+	DefWatchBuffer = 1024
+)
+
+var (
+	ErrWatchOverflow = errors.New("watcher is too slow, events were dropped")
+	ErrHistoryGone   = errors.New("events after the given one are not in the history anymore")
+)
+
+type Event struct {
+	Seq        uint64      `json:"seq"` // grows with every event which has watchers or is kept in history
+	Type       EventType   `json:"type"`
+	Key        string      `json:"key"`
+	OldVersion uint64      `json:"old_version"` // 0 for a key which didn't exist
+	Version    uint64      `json:"version"`     // 0 for a key which is gone
+	Value      interface{} `json:"value"`       // nil for a key which is gone, a container changed in place and containers and bitmaps in the history
+	Change     *Change     `json:"change,omitempty"`
+}
+
+// a container changed in place, watchers get the change instead of a copy of the whole container,
+// args are the fields of the mutation as they go to the log, {"Values": ["a"], "Head": false} of listPush
+type Change struct {
+	Op   string      `json:"op"`
+	Args interface{} `json:"args"`
+}
+
+type Watcher struct {
+	C      <-chan Event // closed by Close, Stop of the store or overflow
+	c      chan Event
+	prefix string
+	ws     *watchers
+	err    error
+}
+
+// events are sent under the lock of watchers only while somebody watches, the history is kept
+// by shards under their own locks, so changes of different shards don't meet on a single lock
+type watchers struct {
+	mu          sync.Mutex
+	count       int32 // read atomically, so changes don't take the lock while nobody watches
+	list        []*Watcher
+	epoch       uint64 // the run of the store, seq starts over with every run
+	seq         uint64 // changed atomically
+	historySize int    // 0 keeps no history
+}
+
+// ring of the last events of a shard, changed under the lock of the shard
+type history struct {
+	events  []Event
+	next    int    // position of the next event in the ring
+	dropped uint64 // seq of the last event pushed out of the ring
+}
+
+// every shard keeps the last n events of its keys, so watchers can resume after the event they have seen,
+// every change gets an event then, even if nobody watches, values of containers and bitmaps are left out,
+// they would be copied on every change, watchers read such keys to get them
+func WithWatchHistory(n int) setting {
+	return func(s *Store) {
+		if n > 0 {
+			s.watchers.historySize = n
+		}
+	}
+}
+
+// changes with every run of the store, seqs of events are comparable only within the same epoch
+func (s *Store) WatchEpoch() uint64 {
+	return s.watchers.epoch
+}
+
+// watches keys with the prefix, "" for all keys, buffer 0 takes DefWatchBuffer
+func (s *Store) Watch(prefix string, buffer int) *Watcher {
+	s.watchers.mu.Lock()
+	defer s.watchers.mu.Unlock()
+	return s.watchers.add(prefix, buffer, nil)
+}
+
+// watches keys with the prefix starting right after the event with seq of the epoch, events from
+// the history come first, fails with ErrHistoryGone if some of the events are not there anymore
+func (s *Store) WatchFrom(prefix string, buffer int, epoch, seq uint64) (*Watcher, error) {
+	var w *Watcher
+	// shards don't emit meanwhile, so no event falls between the history and the watcher
+	err := s.lockedAll(func() error {
+		ws := &s.watchers
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+
+		last := atomic.LoadUint64(&ws.seq)
+		if epoch != ws.epoch || seq > last || seq < last && ws.historySize == 0 {
+			return ErrHistoryGone
+		}
+		var missed []Event
+		for _, sh := range s.shards {
+			if sh.history == nil {
+				continue
+			}
+			if sh.history.dropped > seq {
+				return ErrHistoryGone
+			}
+			missed = sh.history.after(seq, prefix, missed)
+		}
+		sort.Slice(missed, func(a, b int) bool {
+			return missed[a].Seq < missed[b].Seq
+		})
+		w = ws.add(prefix, buffer, missed)
+		return nil
+	})
+	return w, err
+}
+
+// should be called under lock, the buffer fits the missed events on top
+func (ws *watchers) add(prefix string, buffer int, missed []Event) *Watcher {
+	if buffer <= 0 {
+		buffer = DefWatchBuffer
+	}
+	c := make(chan Event, buffer+len(missed))
+	for _, e := range missed {
+		c <- e
+	}
+	w := &Watcher{C: c, c: c, prefix: prefix, ws: ws}
+	ws.list = append(ws.list, w)
+	atomic.AddInt32(&ws.count, 1)
+	return w
+}
+
+// stops the watcher, C is closed once the events in its buffer are read
+func (w *Watcher) Close() {
+	w.ws.mu.Lock()
+	w.ws.remove(w, nil)
+	w.ws.mu.Unlock()
+}
+
+// ErrWatchOverflow once the watcher was closed because of its full buffer
+func (w *Watcher) Err() error {
+	w.ws.mu.Lock()
+	defer w.ws.mu.Unlock()
+	return w.err
+}
+
+// should be called under lock
+func (ws *watchers) remove(w *Watcher, err error) {
+	for n, other := range ws.list {
+		if other == w {
+			ws.list = append(ws.list[:n], ws.list[n+1:]...)
+			atomic.AddInt32(&ws.count, -1)
+			w.err = err
+			close(w.c)
+			return
+		}
+	}
+}
+
+func (ws *watchers) closeAll() {
+	ws.mu.Lock()
+	for len(ws.list) > 0 {
+		ws.remove(ws.list[0], nil)
+	}
+	ws.mu.Unlock()
+}
+
+// should be called under write lock, i is nil for a key which is gone, m is the mutation
+// of a container changed in place, nil for other changes
+func (sh *shard) emit(typ EventType, key string, oldVersion uint64, i *item, m mutation) {
+	ws := sh.watchers
+	if atomic.LoadInt32(&ws.count) == 0 {
+		if sh.history != nil {
+			sh.history.record(newEvent(ws, typ, key, oldVersion, i, m))
+		}
+		return
+	}
+
+	// seq is given under the lock, so watchers get events in order of seq,
+	// the event and a copy of the value are made only once there is a watcher of the key
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	var e *Event
+	event := func() *Event {
+		if e == nil {
+			made := newEvent(ws, typ, key, oldVersion, i, m)
+			e = &made
+		}
+		return e
+	}
+	if sh.history != nil {
+		sh.history.record(*event())
+	}
+	valued := false
+	for n := 0; n < len(ws.list); n++ {
+		w := ws.list[n]
+		if !strings.HasPrefix(key, w.prefix) {
+			continue
+		}
+		if !valued && i != nil && m == nil {
+			event().Value = i.value()
+			valued = true
+		}
+		select {
+		case w.c <- *event():
+		default:
+			ws.remove(w, ErrWatchOverflow)
+			n--
+		}
+	}
+}
+
+// the value goes as it is kept in the history, watchers get a copy
+func newEvent(ws *watchers, typ EventType, key string, oldVersion uint64, i *item, m mutation) Event {
+	e := Event{Seq: atomic.AddUint64(&ws.seq, 1), Type: typ, Key: key, OldVersion: oldVersion}
+	if i != nil {
+		e.Version = i.Version
+		if m == nil {
+			e.Value = i.historyValue()
+		}
+	}
+	if m != nil {
+		e.Change = &Change{Op: reflect.TypeOf(m).Elem().Name(), Args: m}
+	}
+	return e
+}
+
+// values shared with the store are kept as they are, the rest would need a copy
+func (item *item) historyValue() interface{} {
+	switch item.Value.(type) {
+	case container, []byte:
+		return nil
+	}
+	return item.Value
+}
+
+func newHistory(size int) *history {
+	return &history{events: make([]Event, 0, size)}
+}
+
+func (h *history) record(e Event) {
+	if len(h.events) < cap(h.events) {
+		h.events = append(h.events, e)
+		return
+	}
+	h.dropped = h.events[h.next].Seq
+	h.events[h.next] = e
+	h.next = (h.next + 1) % len(h.events)
+}
+
+// appends events after seq of keys with the prefix to result
+func (h *history) after(seq uint64, prefix string, result []Event) []Event {
+	for n := range h.events {
+		e := h.events[(h.next+n)%len(h.events)]
+		if e.Seq > seq && strings.HasPrefix(e.Key, prefix) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
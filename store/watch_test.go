@@ -0,0 +1,222 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	s := newStore(t)
+	w := s.Watch("user:", 0)
+	defer w.Close()
+
+	s.Set("user:1", "alice", time.Minute)
+	s.Set("order:1", "ignored", time.Minute)
+	s.Update("user:1", "alicia", time.Minute)
+	s.Delete("user:1")
+
+	expected := []store.Event{
+		{Seq: 1, Type: store.EventSet, Key: "user:1", Value: "alice"},
+		{Seq: 2, Type: store.EventUpdate, Key: "user:1", Value: "alicia"},
+		{Seq: 3, Type: store.EventDelete, Key: "user:1"},
+	}
+	var version uint64
+	for _, e := range expected {
+		found := <-w.C
+		if found.Seq != e.Seq || found.Type != e.Type || found.Key != e.Key || found.Value != e.Value {
+			t.Errorf("Expected event is %v, but found %v", e, found)
+		}
+		if found.OldVersion != version {
+			t.Errorf("Expected old version is %v, but found %v", version, found.OldVersion)
+		}
+		version = found.Version
+	}
+	if version != 0 {
+		t.Errorf("Expected version 0 of a deleted key, but found %v", version)
+	}
+	select {
+	case e := <-w.C:
+		t.Errorf("Expected no more events, but found %v", e)
+	default:
+	}
+}
+
+func TestWatch_ExpireEvict(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithMaxItems(1),
+	)
+	defer s.Stop()
+	w := s.Watch("", 0)
+	defer w.Close()
+
+	s.Set("someKey", 1, time.Millisecond)
+	<-w.C
+	time.Sleep(time.Millisecond * 1100) // expiration runs once a second
+	if e := <-w.C; e.Type != store.EventExpire || e.Key != "someKey" {
+		t.Errorf("Expected expire of someKey, but found %v", e)
+	}
+
+	s.Set("first", 1, time.Minute)
+	s.Set("second", 2, time.Minute)
+	<-w.C
+	<-w.C
+	if e := <-w.C; e.Type != store.EventEvict {
+		t.Errorf("Expected evict, but found %v", e)
+	}
+}
+
+// new values come as a whole, values changed in place come as their changes
+func TestWatch_Containers(t *testing.T) {
+	s := newStore(t)
+	w := s.Watch("", 0)
+	defer w.Close()
+
+	s.RPush("someList", "a")
+	s.RPush("someList", "b")
+	s.LPop("someList")
+	s.LPop("someList")
+
+	expected := []string{"set [a] ", "update <nil> listPush", "update <nil> listPop", "delete <nil> "}
+	for _, e := range expected {
+		found := <-w.C
+		op := ""
+		if found.Change != nil {
+			op = found.Change.Op
+		}
+		if s := fmt.Sprintf("%v %v %v", found.Type, found.Value, op); s != e {
+			t.Errorf("Expected event is %v, but found %v", e, s)
+		}
+	}
+}
+
+func TestWatch_Overflow(t *testing.T) {
+	s := newStore(t)
+	slow := s.Watch("", 2)
+	fast := s.Watch("", 10)
+	defer fast.Close()
+
+	for n := 0; n < 5; n++ {
+		s.Set(fmt.Sprintf("key%d", n), n, time.Minute)
+	}
+
+	received := 0
+	for range slow.C {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("Expected 2 events before overflow, but found %v", received)
+	}
+	if slow.Err() != store.ErrWatchOverflow {
+		t.Errorf("Expected overflow error, but found %v", slow.Err())
+	}
+	if len(fast.C) != 5 {
+		t.Errorf("Expected 5 events of the other watcher, but found %v", len(fast.C))
+	}
+}
+
+func TestWatch_Close(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+	)
+	w := s.Watch("", 0)
+	s.Set("someKey", 1, time.Minute)
+	w.Close()
+	s.Set("otherKey", 1, time.Minute)
+
+	if e, ok := <-w.C; !ok || e.Key != "someKey" {
+		t.Errorf("Expected buffered event of someKey, but found %v", e)
+	}
+	if _, ok := <-w.C; ok {
+		t.Errorf("Expected closed channel")
+	}
+	if w.Err() != nil {
+		t.Errorf("Expected no error, but found %v", w.Err())
+	}
+
+	other := s.Watch("", 0)
+	s.Stop()
+	if _, ok := <-other.C; ok {
+		t.Errorf("Expected channel closed by Stop")
+	}
+}
+
+func TestWatchFrom(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithWatchHistory(3),
+		store.WithShards(1), // a single shard keeps all 3 events
+	)
+	defer s.Stop()
+	for n := 1; n <= 4; n++ {
+		s.Set(fmt.Sprintf("key%v", n%2), n, time.Minute)
+	}
+
+	w, err := s.WatchFrom("key1", 0, s.WatchEpoch(), 2)
+	if err != nil {
+		t.Fatalf("Expected to resume after event 2, but found %v", err)
+	}
+	defer w.Close()
+	s.Set("key1", 5, time.Minute)
+	for _, seq := range []uint64{3, 5} {
+		if e := <-w.C; e.Seq != seq || e.Key != "key1" {
+			t.Errorf("Expected event %v of key1, but found %v", seq, e)
+		}
+	}
+
+	if _, err = s.WatchFrom("", 0, s.WatchEpoch(), 1); err != store.ErrHistoryGone {
+		t.Errorf("Expected %v after an event out of history, but found %v", store.ErrHistoryGone, err)
+	}
+	if _, err = s.WatchFrom("", 0, s.WatchEpoch(), 10); err != store.ErrHistoryGone {
+		t.Errorf("Expected %v after an event ahead of the store, but found %v", store.ErrHistoryGone, err)
+	}
+	if _, err = s.WatchFrom("", 0, s.WatchEpoch()+1, 2); err != store.ErrHistoryGone {
+		t.Errorf("Expected %v after an event of another run, but found %v", store.ErrHistoryGone, err)
+	}
+	w2, err := s.WatchFrom("", 0, s.WatchEpoch(), 5)
+	if err != nil {
+		t.Fatalf("Expected to resume after the last event, but found %v", err)
+	}
+	defer w2.Close()
+	select {
+	case e := <-w2.C:
+		t.Errorf("Expected no events, but found %v", e)
+	default:
+	}
+}
+
+func TestWatchFrom_Containers(t *testing.T) {
+	s := store.New(
+		store.WithCustomFilename(tempFilename(t)),
+		store.WithWatchHistory(10),
+	)
+	defer s.Stop()
+	w := s.Watch("list", 0)
+	defer w.Close()
+	s.RPush("list", "a", "b")
+	s.RPush("list", "c")
+	s.Set("plain", "value", time.Minute)
+
+	if e := <-w.C; e.Value == nil {
+		t.Errorf("Expected the value of the new list for a watcher, but found %v", e)
+	}
+	if e := <-w.C; e.Value != nil || e.Change == nil || e.Change.Op != "listPush" {
+		t.Errorf("Expected the push without the value of the list, but found %v", e)
+	}
+	w2, err := s.WatchFrom("", 0, s.WatchEpoch(), 0)
+	if err != nil {
+		t.Fatalf("Expected to resume from the start, but found %v", err)
+	}
+	defer w2.Close()
+	if e := <-w2.C; e.Key != "list" || e.Value != nil {
+		t.Errorf("Expected the list without value in the history, but found %v", e)
+	}
+	if e := <-w2.C; e.Change == nil || e.Change.Op != "listPush" {
+		t.Errorf("Expected the push in the history, but found %v", e)
+	}
+	if e := <-w2.C; e.Key != "plain" || e.Value != "value" {
+		t.Errorf("Expected the plain value in the history, but found %v", e)
+	}
+}
@@ -0,0 +1,279 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math"
+)
+
+// ZSet is a native sorted set, members are ordered by score and then by member itself
+
+const errNotANumberFmt = "score of member '%v' is not a number"
+
+func init() {
+	gob.Register(&ZSet{})
+	gob.Register(&zsetAdd{})
+	gob.Register(&zsetRem{})
+	gob.Register(&zsetIncr{})
+}
+
+type ScoredMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+type ZSet struct {
+	scores map[string]float64
+	list   *skipList
+}
+
+func newZSet() interface{} {
+	return &ZSet{
+		scores: make(map[string]float64),
+		list:   newSkipList(),
+	}
+}
+
+func (z *ZSet) len() int {
+	return len(z.scores)
+}
+
+// the skip list can't be walked by reflection
+func (z *ZSet) size() int64 {
+	var size int64
+	for member := range z.scores {
+		size += sizeOfScoredMember(member)
+	}
+	return size
+}
+
+// members in order of scores
+func (z *ZSet) clone() interface{} {
+	return z.rangeByRank(0, z.list.length)
+}
+
+// only scores are stored, the skip list is built again on decoding
+func (z *ZSet) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(z.scores)
+	return buf.Bytes(), err
+}
+
+func (z *ZSet) GobDecode(data []byte) error {
+	var scores map[string]float64
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&scores); err != nil {
+		return err
+	}
+	*z = *newZSet().(*ZSet)
+	for member, score := range scores {
+		z.set(member, score)
+	}
+	return nil
+}
+
+// returns true if the member is new
+func (z *ZSet) set(member string, score float64) bool {
+	old, found := z.scores[member]
+	if found {
+		if old == score {
+			return false
+		}
+		z.list.delete(member, old)
+	}
+	z.scores[member] = score
+	z.list.insert(member, score)
+	return !found
+}
+
+func (z *ZSet) remove(member string) bool {
+	score, found := z.scores[member]
+	if found {
+		delete(z.scores, member)
+		z.list.delete(member, score)
+	}
+	return found
+}
+
+// members between start and stop positions, stop is not included
+func (z *ZSet) rangeByRank(start, stop int) []ScoredMember {
+	result := make([]ScoredMember, 0, stop-start)
+	for x := z.list.byRank(start); x != nil && start < stop; x, start = x.levels[0].next, start+1 {
+		result = append(result, ScoredMember{Member: x.member, Score: x.score})
+	}
+	return result
+}
+
+// returns number of members which were not in the set before, scores of the rest are updated
+func (s *Store) ZAdd(key string, members ...ScoredMember) (int, error) {
+	for _, m := range members {
+		if math.IsNaN(m.Score) {
+			return 0, fmt.Errorf(errNotANumberFmt, m.Member)
+		}
+	}
+
+	m := &zsetAdd{Members: members}
+	err := s.mutate(key, newZSet, m)
+	return m.added, err
+}
+
+type zsetAdd struct {
+	Members []ScoredMember
+	added   int
+}
+
+func (m *zsetAdd) apply(key string, val interface{}) (int64, error) {
+	z, ok := val.(*ZSet)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	var delta int64
+	for _, member := range m.Members {
+		if z.set(member.Member, member.Score) {
+			delta += sizeOfScoredMember(member.Member)
+			m.added++
+		}
+	}
+	return delta, nil
+}
+
+// returns number of members which were removed
+func (s *Store) ZRem(key string, members ...string) (int, error) {
+	m := &zsetRem{Members: members}
+	err := s.mutate(key, nil, m)
+	return m.removed, err
+}
+
+type zsetRem struct {
+	Members []string
+	removed int
+}
+
+func (m *zsetRem) apply(key string, val interface{}) (int64, error) {
+	z, ok := val.(*ZSet)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	var delta int64
+	for _, member := range m.Members {
+		if z.remove(member) {
+			delta -= sizeOfScoredMember(member)
+			m.removed++
+		}
+	}
+	if m.removed == 0 {
+		return 0, errUnchanged
+	}
+	return delta, nil
+}
+
+// adds delta to the score of the member, a missing member starts with 0
+func (s *Store) ZIncrBy(key, member string, delta float64) (float64, error) {
+	m := &zsetIncr{Member: member, Delta: delta}
+	err := s.mutate(key, newZSet, m)
+	return m.score, err
+}
+
+type zsetIncr struct {
+	Member string
+	Delta  float64
+	score  float64
+}
+
+func (m *zsetIncr) apply(key string, val interface{}) (int64, error) {
+	z, ok := val.(*ZSet)
+	if !ok {
+		return 0, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	old, found := z.scores[m.Member]
+	m.score = old + m.Delta
+	if math.IsNaN(m.score) {
+		return 0, fmt.Errorf(errNotANumberFmt, m.Member)
+	}
+	z.set(m.Member, m.score)
+	if found {
+		return 0, nil
+	}
+	return sizeOfScoredMember(m.Member), nil
+}
+
+func (s *Store) ZScore(key, member string) (float64, error) {
+	var score float64
+	err := s.read(key, func(val interface{}) error {
+		z, err := asZSet(key, val)
+		if err != nil {
+			return err
+		}
+		var found bool
+		if score, found = z.scores[member]; !found {
+			return fmt.Errorf(errKeyNotFoundFmt, member)
+		}
+		return nil
+	})
+	return score, err
+}
+
+// 0 based position of the member in order of scores
+func (s *Store) ZRank(key, member string) (int, error) {
+	rank := -1
+	err := s.read(key, func(val interface{}) error {
+		z, err := asZSet(key, val)
+		if err != nil {
+			return err
+		}
+		score, found := z.scores[member]
+		if !found {
+			return fmt.Errorf(errKeyNotFoundFmt, member)
+		}
+		rank = z.list.rank(member, score)
+		return nil
+	})
+	return rank, err
+}
+
+// members with scores from min to max inclusive, math.Inf works for open ranges
+func (s *Store) ZRangeByScore(key string, min, max float64) ([]ScoredMember, error) {
+	result := []ScoredMember{}
+	err := s.read(key, func(val interface{}) error {
+		z, err := asZSet(key, val)
+		if err != nil {
+			return err
+		}
+		for x := z.list.firstFrom(min); x != nil && x.score <= max; x = x.levels[0].next {
+			result = append(result, ScoredMember{Member: x.member, Score: x.score})
+		}
+		return nil
+	})
+	return result, err
+}
+
+// start and stop are inclusive and can be negative as in LRange, -1 is the member with the highest score
+func (s *Store) ZRangeByRank(key string, start, stop int) ([]ScoredMember, error) {
+	result := []ScoredMember{}
+	err := s.read(key, func(val interface{}) error {
+		z, err := asZSet(key, val)
+		if err != nil {
+			return err
+		}
+		start, stop := bounds(z.list.length, start, stop)
+		result = z.rangeByRank(start, stop)
+		return nil
+	})
+	return result, err
+}
+
+// missing key is an empty sorted set
+func asZSet(key string, val interface{}) (*ZSet, error) {
+	if val == nil {
+		return newZSet().(*ZSet), nil
+	}
+	z, ok := val.(*ZSet)
+	if !ok {
+		return nil, fmt.Errorf(errWrongTypeFmt, key)
+	}
+	return z, nil
+}
+
+func sizeOfScoredMember(m string) int64 {
+	return int64(len(m)) + 8
+}
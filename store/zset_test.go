@@ -0,0 +1,153 @@
+package store_test
+
+import (
+	"fmt"
+	"github.com/baratov/golang-playground/store"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestZAddZRem(t *testing.T) {
+	s := newStore(t)
+
+	n, err := s.ZAdd("board",
+		store.ScoredMember{Member: "alice", Score: 30},
+		store.ScoredMember{Member: "bob", Score: 10},
+		store.ScoredMember{Member: "carol", Score: 20})
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 added members, but found %v", n)
+	}
+	// score of an existing member is updated
+	if n, _ = s.ZAdd("board", store.ScoredMember{Member: "bob", Score: 40}); n != 0 {
+		t.Errorf("Expected 0 added members, but found %v", n)
+	}
+
+	expected := []store.ScoredMember{{"carol", 20}, {"alice", 30}, {"bob", 40}}
+	if val, _ := s.Get("board"); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected value is %v, but found %v", expected, val)
+	}
+
+	if n, _ = s.ZRem("board", "carol", "dave"); n != 1 {
+		t.Errorf("Expected 1 removed member, but found %v", n)
+	}
+	if _, err := s.ZScore("board", "carol"); err == nil {
+		t.Errorf("Expected carol to be removed")
+	}
+	_, version, _ := s.GetWithVersion("board")
+	s.ZRem("board", "dave")
+	if _, v, _ := s.GetWithVersion("board"); v != version {
+		t.Errorf("Expected version %v to stay the same, but found %v", version, v)
+	}
+
+	// empty sorted set is removed
+	s.ZRem("board", "alice", "bob")
+	if keys := s.Keys(); len(keys) != 0 {
+		t.Errorf("Expected no keys, but found %v", keys)
+	}
+}
+
+func TestZScoreZRankZIncrBy(t *testing.T) {
+	s := newStore(t)
+	for n := 0; n < 100; n++ {
+		s.ZAdd("board", store.ScoredMember{Member: fmt.Sprintf("m%02d", n), Score: float64(n)})
+	}
+
+	rank, err := s.ZRank("board", "m42")
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if rank != 42 {
+		t.Errorf("Expected rank is 42, but found %v", rank)
+	}
+
+	score, err := s.ZIncrBy("board", "m42", 100)
+	if err != nil {
+		t.Errorf("Error found %s", err.Error())
+	}
+	if score != 142 {
+		t.Errorf("Expected score is 142, but found %v", score)
+	}
+	if rank, _ := s.ZRank("board", "m42"); rank != 99 {
+		t.Errorf("Expected rank is 99, but found %v", rank)
+	}
+	if score, _ := s.ZScore("board", "m42"); score != 142 {
+		t.Errorf("Expected score is 142, but found %v", score)
+	}
+
+	_, err = s.ZRank("board", "nonExisting")
+	expected := "key 'nonExisting' not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+
+	if _, err = s.ZIncrBy("board", "m00", math.NaN()); err == nil {
+		t.Errorf("Expected error for NaN score")
+	}
+}
+
+func TestZRange(t *testing.T) {
+	s := newStore(t)
+	s.ZAdd("board",
+		store.ScoredMember{Member: "a", Score: 1},
+		store.ScoredMember{Member: "b", Score: 2},
+		store.ScoredMember{Member: "c", Score: 2},
+		store.ScoredMember{Member: "d", Score: 3})
+
+	expected := []store.ScoredMember{{"b", 2}, {"c", 2}, {"d", 3}}
+	if val, _ := s.ZRangeByScore("board", 2, math.Inf(1)); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected range is %v, but found %v", expected, val)
+	}
+
+	expected = []store.ScoredMember{{"c", 2}, {"d", 3}}
+	if val, _ := s.ZRangeByRank("board", -2, -1); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected range is %v, but found %v", expected, val)
+	}
+
+	if val, _ := s.ZRangeByRank("board", 0, -100); len(val) != 0 {
+		t.Errorf("Expected empty range, but found %v", val)
+	}
+	if val, _ := s.ZRangeByRank("nonExisting", 0, -1); len(val) != 0 {
+		t.Errorf("Expected empty range, but found %v", val)
+	}
+}
+
+func TestZSet_WrongType(t *testing.T) {
+	s := newStore(t)
+	s.Set("someKey", 123, time.Minute)
+
+	_, err := s.ZAdd("someKey", store.ScoredMember{Member: "a", Score: 1})
+	expected := "wrong type for key 'someKey'"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error is %s, but found %v", expected, err)
+	}
+}
+
+func TestZSet_Restore(t *testing.T) {
+	filename := tempFilename(t)
+
+	s := store.New(
+		store.WithCustomFilename(filename),
+	)
+	s.ZAdd("board",
+		store.ScoredMember{Member: "a", Score: 3},
+		store.ScoredMember{Member: "b", Score: 1})
+	s.Stop()
+
+	r := store.New(
+		store.WithCustomFilename(filename),
+		store.WithRestoreFromFile(filename),
+	)
+	r.ZAdd("board", store.ScoredMember{Member: "c", Score: 2})
+	expected := []store.ScoredMember{{"b", 1}, {"c", 2}, {"a", 3}}
+	if val, _ := r.ZRangeByRank("board", 0, -1); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Expected value is %v, but found %v", expected, val)
+	}
+
+	//teardown
+	r.Stop()
+}